@@ -3,3 +3,5 @@
 go build -ldflags -H=windowsgui ./cmd/pok 
 go build -ldflags -H=windowsgui ./cmd/poked
 go build -ldflags -H=windowsgui ./cmd/dialog-helper
+go build -ldflags -H=windowsgui ./cmd/content-audit
+go build -ldflags -H=windowsgui ./cmd/map-tool
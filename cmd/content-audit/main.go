@@ -0,0 +1,281 @@
+package main
+
+import(
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/atemmel/pok/pkg/constants"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/fonts"
+	"github.com/atemmel/pok/pkg/mapfile"
+	"golang.org/x/image/font"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of findings reported by the audit
+const(
+	KindMissing = "missing"
+	KindUnreferenced = "unreferenced"
+	KindInvalid = "invalid"
+	KindBadMap = "bad-map"
+)
+
+var mapDir *string
+var dialogDir *string
+var audioDir *string
+var builtin *string
+var fontPath *string
+var doJson *bool
+
+func init() {
+	mapDir = flag.String("maps", constants.TileMapDir, "Directory holding the tilemaps")
+	dialogDir = flag.String("dialog", constants.DialogDir, "Directory holding the dialog trees")
+	audioDir = flag.String("audio", constants.AudioDir, "Directory holding the music played on maps")
+	builtin = flag.String("builtin", "surf.json", "Comma separated dialog trees used by the game itself rather than by any map")
+	fontPath = flag.String("font", constants.DialogFontPath, "Font used to measure dialog text")
+	doJson = flag.Bool("json", false, "Reports the audit as JSON")
+}
+
+// Kinds of map objects which refer to dialog trees
+const(
+	ObjectNpc = "npc"
+	ObjectTrigger = "trigger"
+	ObjectInteractable = "interactable"
+)
+
+// Reference is an NPC, trigger or interactable using a dialog tree
+type Reference struct {
+	Map string
+	Object string
+	Index int
+	X, Y, Z int
+}
+
+func (r Reference) String() string {
+	return fmt.Sprintf("%s: %s %d at (%d, %d, %d)", r.Map, r.Object, r.Index, r.X, r.Y, r.Z)
+}
+
+type Finding struct {
+	Kind string
+	File string
+	Message string
+}
+
+type Report struct {
+	Findings []Finding
+	// Trees used more than once along with what uses them
+	Shared map[string][]Reference
+}
+
+// listFiles returns the paths of every file below dir ending with ext,
+// relative to dir and using forward slashes
+func listFiles(dir string, ext string) ([]string, error) {
+	paths := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ext) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+func Audit(maps, dialogs, audio string, builtin []string, face font.Face) (*Report, error) {
+	report := &Report{
+		make([]Finding, 0),
+		make(map[string][]Reference),
+	}
+	add := func(kind, file, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, Finding{kind, file, fmt.Sprintf(format, args...)})
+	}
+
+	mapFiles, err := listFiles(maps, ".json")
+	if err != nil {
+		return nil, err
+	}
+	treeFiles, err := listFiles(dialogs, ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string][]Reference)
+	for _, m := range mapFiles {
+		bytes, err := ioutil.ReadFile(filepath.Join(maps, m))
+		if err != nil {
+			return nil, err
+		}
+		info := mapfile.File{}
+		if _, err = info.Decode(bytes); err != nil {
+			add(KindBadMap, m, "%s", err.Error())
+			continue
+		}
+		if music := info.Properties.Music; music != "" {
+			if _, err := os.Stat(filepath.Join(audio, music)); err != nil {
+				add(KindMissing, m, "plays %s, which does not exist", music)
+			}
+		}
+		for i, npc := range info.NpcInfo {
+			path := filepath.ToSlash(filepath.Clean(npc.DialogPath))
+			refs[path] = append(refs[path], Reference{m, ObjectNpc, i, npc.X, npc.Y, npc.Z})
+		}
+		for i, tr := range info.Triggers {
+			// Triggers performing an effect have no tree
+			if tr.DialogPath == "" {
+				continue
+			}
+			path := filepath.ToSlash(filepath.Clean(tr.DialogPath))
+			refs[path] = append(refs[path], Reference{m, ObjectTrigger, i, tr.X, tr.Y, tr.Z})
+		}
+		for i, in := range info.Interactables {
+			if in.DialogPath == "" {
+				continue
+			}
+			path := filepath.ToSlash(filepath.Clean(in.DialogPath))
+			refs[path] = append(refs[path], Reference{m, ObjectInteractable, i, in.X, in.Y, in.Z})
+		}
+	}
+
+	exists := make(map[string]bool)
+	for _, t := range treeFiles {
+		exists[t] = true
+	}
+
+	used := make(map[string]bool)
+	for _, b := range builtin {
+		used[b] = true
+	}
+
+	referenced := make([]string, 0, len(refs))
+	for path := range refs {
+		referenced = append(referenced, path)
+	}
+	sort.Strings(referenced)
+
+	for _, path := range referenced {
+		used[path] = true
+		if !exists[path] {
+			for _, ref := range refs[path] {
+				add(KindMissing, path, "used by %s but does not exist", ref)
+			}
+		}
+		if len(refs[path]) > 1 {
+			report.Shared[path] = refs[path]
+		}
+	}
+
+	for _, b := range builtin {
+		if !exists[b] {
+			add(KindMissing, b, "used by the game but does not exist")
+		}
+	}
+
+	for _, t := range treeFiles {
+		if !used[t] {
+			add(KindUnreferenced, t, "no map or part of the game uses this tree")
+		}
+
+		tree, err := dialog.ReadDialogTreeFromFile(filepath.Join(dialogs, t))
+		if err != nil {
+			add(KindInvalid, t, "%s", err.Error())
+			continue
+		}
+		validator := dialog.DialogTreeValidator{Face: face}
+		for _, issue := range validator.Validate(tree) {
+			add(KindInvalid, t, "%s", issue.String())
+		}
+	}
+
+	return report, nil
+}
+
+// countUsers counts refs by the kind of object, such as "2 npcs, 1 trigger"
+func countUsers(refs []Reference) string {
+	counts := make(map[string]int)
+	for _, ref := range refs {
+		counts[ref.Object]++
+	}
+
+	parts := make([]string, 0, len(counts))
+	for _, object := range []string{ObjectNpc, ObjectTrigger, ObjectInteractable} {
+		n := counts[object]
+		if n == 0 {
+			continue
+		}
+		part := strconv.Itoa(n) + " " + object
+		if n > 1 {
+			part += "s"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *Report) Print() {
+	for _, f := range r.Findings {
+		fmt.Printf("%s: %s: %s\n", f.Kind, f.File, f.Message)
+	}
+
+	shared := make([]string, 0, len(r.Shared))
+	for path := range r.Shared {
+		shared = append(shared, path)
+	}
+	sort.Strings(shared)
+
+	for _, path := range shared {
+		fmt.Printf("shared: %s is used by %s\n", path, countUsers(r.Shared[path]))
+		for _, ref := range r.Shared[path] {
+			fmt.Printf("\t%s\n", ref)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	face, err := fonts.LoadFont(*fontPath, dialog.FontSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *fontPath, err.Error())
+		os.Exit(1)
+	}
+
+	builtins := make([]string, 0)
+	for _, b := range strings.Split(*builtin, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			builtins = append(builtins, b)
+		}
+	}
+
+	report, err := Audit(*mapDir, *dialogDir, *audioDir, builtins, face)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if *doJson {
+		bytes, err := json.Marshal(report)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(bytes))
+	} else {
+		report.Print()
+	}
+
+	if len(report.Findings) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import(
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	root, err := ioutil.TempDir("", "content-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"maps/town.json": `{"Properties": {"Music": "town.mp3"}, "NpcInfo": [
+			{"DialogPath": "shop.json", "X": 1, "Y": 2},
+			{"DialogPath": "gone.json", "X": 3, "Y": 4},
+			{"DialogPath": "shop.json", "X": 5, "Y": 6}
+		]}`,
+		"maps/route.json": `{"NpcInfo": [{"DialogPath": "broken.json"}], "Triggers": [
+			{"DialogPath": "sign.json", "X": 2, "Y": 2},
+			{"Effect": "set_flag seen"}
+		], "Interactables": [{"DialogPath": "sign.json", "X": 4, "Y": 1}],
+		"Properties": {"Music": "gone.mp3"}}`,
+		"maps/garbled.json": `{"NpcInfo": `,
+		"dialog/shop.json": `[{"Type":"Dialog","Data":{"Dialog":"Welcome!","Next":null}}]`,
+		"dialog/broken.json": `[{"Type":"Dialog","Data":{"Dialog":"Oops","Next":4}}]`,
+		"dialog/surf.json": `[{"Type":"Dialog","Data":{"Dialog":"Surf","Next":null}}]`,
+		"dialog/sign.json": `[{"Type":"Dialog","Data":{"Dialog":"Route 1","Next":null}}]`,
+		"dialog/old.json": `[{"Type":"Dialog","Data":{"Dialog":"Old","Next":null}}]`,
+		"audio/town.mp3": ``,
+	}
+
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err = ioutil.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := Audit(filepath.Join(root, "maps"), filepath.Join(root, "dialog"), filepath.Join(root, "audio"), []string{"surf.json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type finding struct {
+		Kind string
+		File string
+	}
+
+	want := []finding{
+		{KindBadMap, "garbled.json"},
+		{KindMissing, "route.json"},
+		{KindMissing, "gone.json"},
+		{KindInvalid, "broken.json"},
+		{KindUnreferenced, "old.json"},
+	}
+
+	if len(report.Findings) != len(want) {
+		t.Fatalf("Expected %d findings, got %v", len(want), report.Findings)
+	}
+	for i := range want {
+		if report.Findings[i].Kind != want[i].Kind || report.Findings[i].File != want[i].File {
+			t.Errorf("Expected finding %v, got %v", want[i], report.Findings[i])
+		}
+	}
+
+	if len(report.Shared) != 2 || len(report.Shared["shop.json"]) != 2 || len(report.Shared["sign.json"]) != 2 {
+		t.Errorf("Expected shop.json and sign.json to be shared, got %v", report.Shared)
+	}
+}
+
+func TestCountUsers(t *testing.T) {
+	refs := []Reference{
+		{"a.json", ObjectInteractable, 0, 0, 0, 0},
+		{"a.json", ObjectNpc, 0, 0, 0, 0},
+		{"b.json", ObjectNpc, 1, 0, 0, 0},
+	}
+	if got := countUsers(refs); got != "2 npcs, 1 interactable" {
+		t.Errorf("Expected \"2 npcs, 1 interactable\", got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import(
+	"encoding/json"
+	"fmt"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/locale"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func loadTableOrEmpty(path string) locale.Table {
+	table, err := locale.LoadTable(path)
+	if os.IsNotExist(err) {
+		return locale.Table{}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		os.Exit(1)
+	}
+	return table
+}
+
+// loadTables loads the string table of every locale in dir, where a missing
+// directory has no locales
+func loadTables(dir string) map[string]locale.Table {
+	tables := make(map[string]locale.Table)
+	locales, err := locale.Locales(dir)
+	if os.IsNotExist(err) {
+		return tables
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", dir, err.Error())
+		os.Exit(1)
+	}
+	for _, l := range locales {
+		tables[l] = loadTableOrEmpty(locale.Path(dir, l))
+	}
+	return tables
+}
+
+// keyPrefix names the keys extracted from the tree at path after its file
+func keyPrefix(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.Map(func(r rune) rune {
+		if locale.IsKeyChar(r) {
+			return r
+		}
+		return '_'
+	}, base)
+}
+
+// extractFile moves the text of the tree at path into table, leaving
+// references to the new keys in its place. Keys already in the table keep
+// their text unless overwrite is set, and the text stays in the tree.
+func extractFile(path string, table locale.Table, overwrite bool) int {
+	tree, err := dialog.ReadDialogTreeFromFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		os.Exit(1)
+	}
+
+	prefix := keyPrefix(path)
+	extracted := 0
+	for i, node := range *tree {
+		for j, text := range dialog.NodeTexts(node) {
+			if _, ok := locale.Key(*text); ok || *text == "" {
+				continue
+			}
+
+			key := prefix + "." + strconv.Itoa(i)
+			if j > 0 {
+				key += "." + strconv.Itoa(j - 1)
+			}
+			str := locale.Text(*text)
+			if old, ok := table[key]; ok && old != str && !overwrite {
+				fmt.Fprintf(os.Stderr, "%s: %s already has a text, pass -update to replace it\n", path, key)
+				continue
+			}
+			table[key] = str
+			*text = locale.Ref(key)
+			extracted++
+		}
+	}
+
+	bytes, err := json.Marshal(tree)
+	if err != nil {
+		panic(err)
+	}
+	ioutil.WriteFile(path, bytes, 0644)
+	return extracted
+}
+
+func extractFiles(tablePath string) {
+	table := loadTableOrEmpty(tablePath)
+	for _, s := range files {
+		n := extractFile(s, table, *doUpdate)
+		fmt.Printf("%s: extracted %d texts\n", s, n)
+	}
+	if err := table.Save(tablePath); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", tablePath, err.Error())
+		os.Exit(1)
+	}
+}
+
+// mergeFiles adds the keys of the table at basePath to every table given
+func mergeFiles(basePath string) {
+	base := loadTableOrEmpty(basePath)
+	for _, s := range files {
+		table := loadTableOrEmpty(s)
+		added := table.Merge(base)
+		if err := table.Save(s); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", s, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("%s: added %d keys\n", s, added)
+	}
+}
+
+// referencedKeys returns the keys the trees at paths refer to
+func referencedKeys(paths []string) ([]string, []fileIssue) {
+	keys := make([]string, 0)
+	issues := make([]fileIssue, 0)
+	for _, path := range paths {
+		tree, err := dialog.ReadDialogTreeFromFile(path)
+		if err != nil {
+			issues = append(issues, fileIssue{path, -1, "parse", err.Error()})
+			continue
+		}
+		for _, node := range *tree {
+			for _, text := range dialog.NodeTexts(node) {
+				if key, ok := locale.Key(*text); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys, issues
+}
+
+// missingKeys reports, for every locale in dir, the keys used by the given
+// trees or found in the default table which the locale has no text for,
+// along with texts with broken markup
+func missingKeys(dir string) []fileIssue {
+	keys, issues := referencedKeys(files)
+
+	base := loadTableOrEmpty(locale.Path(dir, locale.DefaultLocale))
+	seen := make(map[string]bool)
+	all := make([]string, 0, len(keys) + len(base))
+	for _, key := range append(keys, base.Keys()...) {
+		if !seen[key] {
+			seen[key] = true
+			all = append(all, key)
+		}
+	}
+
+	locales, err := locale.Locales(dir)
+	if err != nil {
+		return append(issues, fileIssue{dir, -1, "parse", err.Error()})
+	}
+
+	for _, l := range locales {
+		path := locale.Path(dir, l)
+		table, err := locale.LoadTable(path)
+		if err != nil {
+			issues = append(issues, fileIssue{path, -1, "parse", err.Error()})
+			continue
+		}
+		for _, key := range table.Missing(all) {
+			issues = append(issues, fileIssue{path, -1, "missing-key", key})
+		}
+		for _, key := range table.Keys() {
+			if _, err := dialog.ParseMarkup(table[key], nil); err != nil {
+				issues = append(issues, fileIssue{path, -1, dialog.IssueBadMarkup, key + ": " + err.Error()})
+			}
+		}
+	}
+
+	return issues
+}
@@ -5,39 +5,105 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/atemmel/pok/pkg/constants"
 	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/fonts"
+	"github.com/atemmel/pok/pkg/locale"
+	"golang.org/x/image/font"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 var files []string
 var doValidate *bool
+var doPrint *bool
+var doJson *bool
+var doDecompile *bool
+var graphFormat *string
+var fontPath *string
+var extractTo *string
+var mergeFrom *string
+var doMissing *bool
+var langDir *string
+var doSimulate bool
+var choicesArg *string
+var flagsArg *string
+var simulateLang *string
+var doUpdate *bool
+var face font.Face
+var tables map[string]locale.Table
 
 func init() {
-	doValidate = flag.Bool("validate", false, "Validates files to be correct JSON")
+	doValidate = flag.Bool("validate", false, "Validates dialog trees, exiting with a non-zero status if any issues are found")
+	doPrint = flag.Bool("print", false, "Prints each dialog tree while validating")
+	doJson = flag.Bool("json", false, "Reports validation issues as JSON")
+	graphFormat = flag.String("graph", "", "Exports dialog trees as graphs, either \""+dialog.GraphDot+"\" or \""+dialog.GraphMermaid+"\"")
+	fontPath = flag.String("font", constants.DialogFontPath, "Font used to measure dialog text")
+	doDecompile = flag.Bool("decompile", false, "Decompiles dialog trees back into dialog scripts")
+	extractTo = flag.String("extract", "", "Moves the text of dialog trees into the given string table, leaving references behind")
+	mergeFrom = flag.String("merge", "", "Adds keys of the given string table missing from the string tables passed as arguments")
+	doMissing = flag.Bool("missing", false, "Reports keys which any locale lacks, among those used by the given dialog trees and those of the default locale")
+	choicesArg = flag.String("choices", "", "Comma separated answers given when simulating, such as 0,1")
+	flagsArg = flag.String("flags", "", "Comma separated flags set before simulating, such as met=true,badges=2")
+	simulateLang = flag.String("lang", "", "Locale text is looked up in when simulating")
+	doUpdate = flag.Bool("update", false, "Replaces golden files with the transcripts of their scenarios, or keys which already have a text when extracting")
+	langDir = flag.String("lang-dir", constants.LangDir, "Directory holding the string table of each locale")
+}
+
+type fileIssue struct {
+	File string
+	Node int
+	Kind string
+	Message string
 }
 
 func ptr(value int) *int {
 	return &value
 }
 
-func validateFile(str string) {
-	bytes, err := ioutil.ReadFile(str)
+func validateFile(path string) []fileIssue {
+	tree, err := dialog.ReadDialogTreeFromFile(path)
 	if err != nil {
-		panic(err)
+		return []fileIssue{
+			{path, -1, "parse", err.Error()},
+		}
 	}
 
-	tree := &dialog.DialogTree{}
-	err = json.Unmarshal(bytes, tree)
-	if err != nil {
-		panic(err)
+	if *doPrint {
+		printer := dialog.DialogTreePrinter{}
+		printer.Print(tree)
 	}
 
-	fmt.Println(tree)
+	validator := dialog.DialogTreeValidator{Face: face, Tables: tables}
+	issues := validator.Validate(tree)
+	result := make([]fileIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, fileIssue{
+			path,
+			issue.Node,
+			issue.Kind,
+			issue.Message,
+		})
+	}
+	return result
+}
 
-	printer := dialog.DialogTreePrinter{}
-	printer.Print(tree)
+func reportIssues(issues []fileIssue) {
+	if *doJson {
+		bytes, err := json.Marshal(issues)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(bytes))
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Printf("%s:%d: %s: %s\n", issue.File, issue.Node, issue.Kind, issue.Message)
+	}
 }
 
 func transpileToDialogJson(path string) dialog.DialogTree {
@@ -47,7 +113,7 @@ func transpileToDialogJson(path string) dialog.DialogTree {
 	}
 
 	str := string(bytes)
-	err = validateLines(str)
+	err = validateLines(face, str)
 	if err != nil {
 		panic(err)
 	}
@@ -95,45 +161,167 @@ func transpileToDialogJson(path string) dialog.DialogTree {
 	return tree
 }
 
-func genFilename(original string, extension string) string {
-	other := original
-	if i := strings.Index(original, "."); i != -1 {
-		other = other[:i] + extension
-	} else {
-		other += extension
+func compileScript(path string) dialog.DialogTree {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
 	}
-	return other
-}
 
-func validateLines(lines string) error {
-	counter := 0
-	lineNo := 1
-	for i := range lines {
-		if counter > dialog.MaxLetters {
-			return errors.New("Too long line encountered on line " + strconv.Itoa(lineNo))
+	tree, err := dialog.CompileScript(string(bytes))
+	if err != nil {
+		if scriptErr, ok := err.(*dialog.ScriptError); ok {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, scriptErr.Line, scriptErr.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
 		}
+		os.Exit(1)
+	}
+
+	return tree
+}
+
+func decompileFile(path string) string {
+	tree, err := dialog.ReadDialogTreeFromFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		os.Exit(1)
+	}
+
+	src, err := dialog.Decompile(tree)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		os.Exit(1)
+	}
+
+	return src
+}
+
+func graphFile(path string) string {
+	tree, err := dialog.ReadDialogTreeFromFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		os.Exit(1)
+	}
+
+	grapher := dialog.DialogTreeGrapher{}
+	graph, err := grapher.Graph(tree, *graphFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	return graph
+}
+
+func graphExtension(format string) string {
+	if format == dialog.GraphMermaid {
+		return ".mmd"
+	}
+	return ".dot"
+}
 
-		counter++
-		if lines[i] == '\n' {
-			lineNo++
-			counter = 0
+func genFilename(original string, extension string) string {
+	return strings.TrimSuffix(original, filepath.Ext(original)) + extension
+}
+
+// validateLines makes sure every word fits on a line of the dialog box, as
+// lines are otherwise wrapped when shown
+func validateLines(face font.Face, lines string) error {
+	markup, err := dialog.ParseMarkup(lines, nil)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range strings.Split(markup.String(), "\n") {
+		if words := dialog.LongWords(face, line, dialog.TextWidth); len(words) > 0 {
+			return errors.New("Too wide word " + strconv.Quote(words[0]) + " encountered on line " + strconv.Itoa(i + 1))
 		}
 	}
 
 	return nil
 }
 
+// simulateCommand runs dialog trees, or scenarios ending in
+// scenarioExtension which are compared with their golden files. It is given
+// before the flags, as in "dialog-helper simulate -choices 0,1 tree.dialog".
+const simulateCommand = "simulate"
+
 func main() {
-	flag.Parse()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] files...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s %s [flags] files...\n", os.Args[0], simulateCommand)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s runs dialog trees, or scenarios ending in %q which are compared with their golden files\n", simulateCommand, scenarioExtension)
+		flag.PrintDefaults()
+	}
+	if len(os.Args) > 1 && os.Args[1] == simulateCommand {
+		doSimulate = true
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 	files = flag.Args()
+
+	var err error
+	face, err = fonts.LoadFont(*fontPath, dialog.FontSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *fontPath, err.Error())
+		os.Exit(1)
+	}
+
+	if *doMissing {
+		issues := missingKeys(*langDir)
+		reportIssues(issues)
+		if len(issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if files != nil && len(files) > 0 {
-		if *doValidate {
+		if doSimulate {
+			simulateFiles()
+		} else if *extractTo != "" {
+			extractFiles(*extractTo)
+		} else if *mergeFrom != "" {
+			mergeFiles(*mergeFrom)
+		} else if *doValidate {
+			tables = loadTables(*langDir)
+			issues := make([]fileIssue, 0)
 			for _, s := range files {
-				validateFile(s)
+				issues = append(issues, validateFile(s)...)
+			}
+			reportIssues(issues)
+			if len(issues) > 0 {
+				os.Exit(1)
+			}
+		} else if *graphFormat != "" {
+			if !dialog.IsValidGraphFormat(*graphFormat) {
+				fmt.Fprintf(os.Stderr, "Unknown graph format %q\n", *graphFormat)
+				os.Exit(1)
+			}
+			for _, s := range files {
+				graph := graphFile(s)
+				filename := genFilename(s, graphExtension(*graphFormat))
+				ioutil.WriteFile(filename, []byte(graph), 0644)
+			}
+		} else if *doDecompile {
+			for _, s := range files {
+				src := decompileFile(s)
+				filename := genFilename(s, dialog.ScriptExtension)
+				ioutil.WriteFile(filename, []byte(src), 0644)
 			}
 		} else {
 			for _, s := range files {
-				tree := transpileToDialogJson(s)
+				var tree dialog.DialogTree
+				if strings.HasSuffix(s, dialog.ScriptExtension) {
+					tree = compileScript(s)
+				} else {
+					tree = transpileToDialogJson(s)
+				}
+				if err := dialog.ValidateMarkup(&tree); err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", s, err.Error())
+					os.Exit(1)
+				}
 				bytes, err := json.Marshal(&tree)
 				if err != nil {
 					panic(err)
@@ -1,6 +1,14 @@
 package main
 
-import "testing"
+import(
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/locale"
+	"golang.org/x/image/font/basicfont"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestGenFilename(t *testing.T) {
 	type genFilenameTest struct {
@@ -13,6 +21,8 @@ func TestGenFilename(t *testing.T) {
 		{"test", ".ext", "test.ext"},
 		{"test.other", ".ext", "test.ext"},
 		{"important_dialog.txt", ".dialog", "important_dialog.dialog"},
+		{"./resources/dialog/npc.json", ".dot", "./resources/dialog/npc.dot"},
+		{"npc.v2.json", ".mmd", "npc.v2.mmd"},
 	}
 
 	for _, test := range tests {
@@ -33,15 +43,18 @@ func TestValidateLines(t *testing.T) {
 String string string string string string
 String string string string string string
 String string string string string string`, true},
-		{`String string string string string string string`, false},
+		{`String string string string string string string`, true},
 		{`String string string string string string
 String string string string string string
-String string string string string string string
+Stringstringstringstringstringstringstring
 String string string string string string`, false},
+		{`<color=red>String string string string string</color> string`, true},
+		{`<color=red>String string`, false},
 	}
 
 	for _, test := range tests {
-		output := validateLines(test.In1)
+		// basicfont fits 32 letters on a line
+		output := validateLines(basicfont.Face7x13, test.In1)
 		if (test.ShouldSucceed && output != nil) || (!test.ShouldSucceed && output == nil) {
 			negative := " "
 			if !test.ShouldSucceed {
@@ -51,3 +64,60 @@ String string string string string string`, false},
 		}
 	}
 }
+
+func TestKeyPrefix(t *testing.T) {
+	type keyPrefixTest struct {
+		In string
+		Want string
+	}
+
+	tests := []keyPrefixTest{
+		{"resources/dialog/surf.json", "surf"},
+		{"old man's tale.json", "old_man_s_tale"},
+	}
+
+	for _, test := range tests {
+		if output := keyPrefix(test.In); output != test.Want {
+			t.Errorf("Output %q not equal to %q", output, test.Want)
+		}
+	}
+}
+
+func TestExtractKeepsTexts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sign.json")
+
+	type extractTest struct {
+		Overwrite bool
+		WantTable string
+		WantTree string
+	}
+
+	tests := []extractTest{
+		{false, "Edited by a translator", "Welcome!"},
+		{true, "Welcome!", "$sign.0"},
+	}
+
+	for _, test := range tests {
+		src := `[{"Type":"Dialog","Data":{"Dialog":"Welcome!","Next":null}}]`
+		if err = ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		table := locale.Table{"sign.0": "Edited by a translator"}
+
+		extractFile(path, table, test.Overwrite)
+
+		tree, err := dialog.ReadDialogTreeFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := (*tree)[0].(*dialog.DialogNode).Dialog
+		if table["sign.0"] != test.WantTable || got != test.WantTree {
+			t.Errorf("overwrite %t: expected %q and %q, got %q and %q", test.Overwrite, test.WantTable, test.WantTree, table["sign.0"], got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import(
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/locale"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const(
+	scenarioExtension = ".sim"
+	goldenExtension = ".golden"
+)
+
+// scenario describes a simulation whose transcript is kept in a golden file
+// next to it. Tree is relative to the directory of the scenario, and text is
+// looked up in Locale if one is given.
+type scenario struct {
+	Tree string
+	Choices []int
+	Flags dialog.Flags
+	Locale string
+}
+
+func loadTree(path string) (*dialog.DialogTree, error) {
+	if strings.HasSuffix(path, dialog.ScriptExtension) {
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := dialog.CompileScript(string(bytes))
+		return &tree, err
+	}
+	return dialog.ReadDialogTreeFromFile(path)
+}
+
+func parseChoices(str string) ([]int, error) {
+	choices := make([]int, 0)
+	for _, field := range strings.Split(str, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		choice, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, errors.New("Choice " + strconv.Quote(field) + " is not a number")
+		}
+		choices = append(choices, choice)
+	}
+	return choices, nil
+}
+
+func parseFlags(str string) (dialog.Flags, error) {
+	flags := make(dialog.Flags)
+	for _, field := range strings.Split(str, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		pair := strings.SplitN(field, "=", 2)
+		if len(pair) != 2 || pair[0] == "" {
+			return nil, errors.New("Flag " + strconv.Quote(field) + " is not of the form NAME=VALUE")
+		}
+		flags[pair[0]] = pair[1]
+	}
+	return flags, nil
+}
+
+func simulator(lang string) (dialog.Simulator, error) {
+	if lang == "" {
+		return dialog.Simulator{}, nil
+	}
+	if err := locale.Use(*langDir, lang); err != nil {
+		return dialog.Simulator{}, err
+	}
+	return dialog.Simulator{Text: locale.Text}, nil
+}
+
+// simulateTree prints the transcript of the tree at path, answering with the
+// choices and starting from the flags given on the command line
+func simulateTree(path string) bool {
+	fail := func(err error) bool {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		return false
+	}
+
+	tree, err := loadTree(path)
+	if err != nil {
+		return fail(err)
+	}
+	choices, err := parseChoices(*choicesArg)
+	if err != nil {
+		return fail(err)
+	}
+	flags, err := parseFlags(*flagsArg)
+	if err != nil {
+		return fail(err)
+	}
+	sim, err := simulator(*simulateLang)
+	if err != nil {
+		return fail(err)
+	}
+
+	transcript := sim.Run(tree, flags, choices)
+	fmt.Printf("== %s\n%s", path, transcript.String())
+	return transcript.Err == nil
+}
+
+// simulateScenario compares the transcript of the scenario at path with its
+// golden file, or replaces the golden file if asked to
+func simulateScenario(path string) bool {
+	fail := func(err error) bool {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+		return false
+	}
+
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	sc := scenario{}
+	if err = json.Unmarshal(bytes, &sc); err != nil {
+		return fail(err)
+	}
+	tree, err := loadTree(filepath.Join(filepath.Dir(path), sc.Tree))
+	if err != nil {
+		return fail(err)
+	}
+	sim, err := simulator(sc.Locale)
+	if err != nil {
+		return fail(err)
+	}
+
+	got := sim.Run(tree, sc.Flags, sc.Choices).String()
+	golden := strings.TrimSuffix(path, scenarioExtension) + goldenExtension
+
+	if *doUpdate {
+		if err = ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			return fail(err)
+		}
+		fmt.Printf("%s: updated %s\n", path, golden)
+		return true
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		return fail(errors.New(err.Error() + ", run with -update to create it"))
+	}
+	if string(want) != got {
+		fmt.Fprintf(os.Stderr, "%s: transcript differs from %s\n--- want\n%s+++ got\n%s", path, golden, string(want), got)
+		return false
+	}
+
+	fmt.Printf("%s: ok\n", path)
+	return true
+}
+
+func simulateFiles() {
+	ok := true
+	for _, s := range files {
+		if strings.HasSuffix(s, scenarioExtension) {
+			ok = simulateScenario(s) && ok
+		} else {
+			ok = simulateTree(s) && ok
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import(
+	"flag"
+	"fmt"
+	"github.com/atemmel/pok/pkg/constants"
+	"github.com/atemmel/pok/pkg/mapfile"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type command struct {
+	Usage string
+	Run func(args []string) error
+}
+
+var commands map[string]command
+var commandNames []string
+
+func init() {
+	commands = make(map[string]command)
+	addCommand("migrate", "migrate [-n] DIR\tupgrades every map in DIR to the current format", migrate)
+	addCommand("convert", "convert -to ENCODING PATH...\twrites the layers of maps, or of every map in directories, as json or zlib", convert)
+	addCommand("import", "import TILED MAP\tconverts the Tiled map TILED, a .tmx or .json file, into MAP", importTiled)
+	addCommand("export", "export [-images DIR] MAP TILED\tconverts MAP into the Tiled map TILED, a .tmx or .json file", exportTiled)
+}
+
+func addCommand(name, usage string, run func(args []string) error) {
+	commands[name] = command{usage, run}
+	commandNames = append(commandNames, name)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: map-tool COMMAND [ARGS]")
+	for _, name := range commandNames {
+		fmt.Fprintln(os.Stderr, "\t" + commands[name].Usage)
+	}
+	os.Exit(2)
+}
+
+// mapFiles returns every map below dir
+func mapFiles(dir string) ([]string, error) {
+	paths := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("n", false, "Lists the maps which would be migrated without changing them")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+
+	paths, err := mapFiles(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		migrated, from, err := mapfile.Migrate(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+			failed = true
+			continue
+		}
+		if string(migrated) == string(data) {
+			continue
+		}
+
+		if from == mapfile.FormatVersion {
+			fmt.Printf("%s: stamped with version %d\n", path, from)
+		} else {
+			fmt.Printf("%s: version %d -> %d\n", path, from, mapfile.FormatVersion)
+		}
+		if !*dryRun {
+			if err = ioutil.WriteFile(path, migrated, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("Some maps could not be migrated")
+	}
+	return nil
+}
+
+func convert(args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := flags.String("to", "", "Encoding to write maps with, either " + strings.Join(mapfile.Encodings, " or "))
+	flags.Parse(args)
+	if flags.NArg() == 0 || !mapfile.IsValidEncoding(*to) {
+		usage()
+	}
+
+	paths := make([]string, 0)
+	for _, arg := range flags.Args() {
+		found, err := mapFiles(arg)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, found...)
+	}
+
+	failed := false
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		converted, err := convertData(data, *to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, err.Error())
+			failed = true
+			continue
+		}
+		if string(converted) == string(data) {
+			continue
+		}
+
+		fmt.Printf("%s: %d -> %d bytes\n", path, len(data), len(converted))
+		if err = ioutil.WriteFile(path, converted, 0644); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("Some maps could not be converted")
+	}
+	return nil
+}
+
+// convertData upgrades the map held in data and writes it using encoding
+func convertData(data []byte, encoding string) ([]byte, error) {
+	migrated, _, err := mapfile.Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	m, err := mapfile.Decode(migrated)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.Encoding()
+	if err != nil {
+		return nil, err
+	}
+	if current == encoding && string(migrated) == string(data) {
+		return data, nil
+	}
+	return mapfile.Pack(migrated, encoding)
+}
+
+// tiledFormat tells the format of a Tiled map from its extension
+func tiledFormat(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".tmx") {
+		return mapfile.TiledTMX
+	}
+	return mapfile.TiledJSON
+}
+
+func printWarnings(path string, warnings mapfile.Warnings) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", path, warning)
+	}
+}
+
+func importTiled(args []string) error {
+	if len(args) != 2 {
+		usage()
+	}
+	src, dst := args[0], args[1]
+
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	tm, err := mapfile.DecodeTiled(data, tiledFormat(src))
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err.Error())
+	}
+	data, warnings, err := mapfile.FromTiled(tm)
+	printWarnings(src, warnings)
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err.Error())
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+func exportTiled(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	images := flags.String("images", constants.TileMapImagesDir, "Directory holding the textures of the map")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		usage()
+	}
+	src, dst := flags.Arg(0), flags.Arg(1)
+
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	// Tiled looks for images relative to the map
+	imagePath, err := filepath.Abs(*images)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(filepath.Dir(dst))
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(dir, imagePath); err == nil {
+		imagePath = rel
+	}
+	imagePath = filepath.ToSlash(imagePath) + "/"
+
+	tm, warnings, err := mapfile.ToTiled(data, *images, imagePath)
+	printWarnings(src, warnings)
+	if err != nil {
+		return fmt.Errorf("%s: %s", src, err.Error())
+	}
+	data, err = mapfile.EncodeTiled(tm, tiledFormat(dst))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	if err := cmd.Run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
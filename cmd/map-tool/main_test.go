@@ -0,0 +1,32 @@
+package main
+
+import(
+	"github.com/atemmel/pok/pkg/mapfile"
+	"strings"
+	"testing"
+)
+
+func TestMigrateKeepsEncoding(t *testing.T) {
+	old := `{"Tiles":[1,2],"Collision":[true,false],"Width":2,"Height":1}`
+
+	packed, err := convertData([]byte(old), mapfile.EncodingZlib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(packed), `"FormatVersion":2`) {
+		t.Errorf("Expected the map to be migrated, got %s", packed)
+	}
+
+	migrated, from, err := mapfile.Migrate(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != mapfile.FormatVersion || string(migrated) != string(packed) {
+		t.Errorf("Expected a current map to be left alone, got %s from version %d", migrated, from)
+	}
+
+	_, encoding, err := mapfile.Unpack(migrated)
+	if err != nil || encoding != mapfile.EncodingZlib {
+		t.Errorf("Expected encoding %q, got %q: %v", mapfile.EncodingZlib, encoding, err)
+	}
+}
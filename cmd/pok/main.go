@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/atemmel/pok/pkg/constants"
 	"github.com/atemmel/pok/pkg/debug"
+	"github.com/atemmel/pok/pkg/locale"
 	"github.com/atemmel/pok/pkg/pok"
 	"github.com/atemmel/pok/pkg/textures"
+	"github.com/atemmel/pok/pkg/tileset"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -15,10 +17,12 @@ var LogFileName string = "error.log"
 var onlineEnabled = false
 var isServing = false
 var fileToOpen string
+var lang string
 
 func init() {
 	debug.InitAssert(&LogFileName, false)
 	flag.BoolVar(&isServing, "serve", false, "Run as game server")
+	flag.StringVar(&lang, "lang", locale.DefaultLocale, "Language to play in")
 	flag.Parse()
 
 	if onlineEnabled {
@@ -45,12 +49,14 @@ func main() {
 	ebiten.SetWindowResizable(true)
 
 	textures.Init()
+	debug.Assert(tileset.LoadAll(constants.TilesetDir))
+	debug.Assert(locale.Use(constants.LangDir, lang))
 	game := pok.CreateGame()
+	game.Audio = pok.NewAudio()
+	debug.Assert(game.LoadWorld(constants.WorldPath))
 
-	game.Load(fileToOpen, 0)
+	debug.Assert(game.Load(fileToOpen, 0))
 	defer game.Save()
-	game.Audio = pok.NewAudio()
-	game.PlayAudio()
 
 	if onlineEnabled {
 		game.Client = pok.CreateClient()
@@ -6,6 +6,7 @@ import (
 	"github.com/atemmel/pok/pkg/debug"
 	"github.com/atemmel/pok/pkg/pok"
 	"github.com/atemmel/pok/pkg/textures"
+	"github.com/atemmel/pok/pkg/tileset"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -17,6 +18,7 @@ func main() {
 	log := "editorerror.log"
 	debug.InitAssert(&log, true)
 	textures.Init()
+	debug.Assert(tileset.LoadAll(constants.TilesetDir))
 	ed := pok.NewEditor(flag.Args())
 
 	ebiten.SetWindowSize(constants.WindowSizeX, constants.WindowSizeY)
@@ -14,8 +14,12 @@ const (
 	FontsDir = ResourceDir + "fonts/"
 	AudioDir = ResourceDir + "audio/"
 	DialogDir = ResourceDir + "dialog/"
+	LangDir = ResourceDir + "lang/"
+	TilesetDir = ResourceDir + "tilesets/"
+	WorldPath = ResourceDir + "world.json"
 	TileMapImagesDir = ImagesDir + "overworld/"
 	CharacterImagesDir = ImagesDir + "characters/"
+	DialogFontPath = FontsDir + "pokemon_pixel_font.ttf"
 
 	EditorResourceDir = "./editorresources/"
 	EditorImagesDir = EditorResourceDir + "images/"
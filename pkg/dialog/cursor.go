@@ -0,0 +1,75 @@
+package dialog
+
+import(
+	"fmt"
+)
+
+// DialogCursor is where a collector stands within a tree, in a form which can
+// be saved and restored later on. Path names the file the tree was read from,
+// Node is -1 once the dialog has ended and Choice is the answer highlighted
+// while a question waits to be answered.
+type DialogCursor struct {
+	Path string
+	Node int
+	Choice int
+}
+
+// SetPath records which file the tree of the collector was read from
+func (coll *DialogTreeCollector) SetPath(path string) {
+	coll.path = path
+}
+
+// Highlight records which answer is highlighted to the node currently
+// waiting for one
+func (coll *DialogTreeCollector) Highlight(choice int) {
+	coll.choice = choice
+}
+
+func (coll *DialogTreeCollector) Cursor() DialogCursor {
+	node := -1
+	if coll.node() != nil {
+		node = *coll.current
+	}
+	return DialogCursor{
+		coll.path,
+		node,
+		coll.choice,
+	}
+}
+
+// nOptions returns how many answers node can be given
+func nOptions(node DialogNodeInterface) int {
+	switch node := node.(type) {
+		case *BinaryDialogNode:
+			return 2
+		case *ChoiceDialogNode:
+			return len(node.Results)
+	}
+	return 0
+}
+
+// Restore moves the collector to where cursor stands. The collector should
+// have been made for the tree found at the path of the cursor.
+func (coll *DialogTreeCollector) Restore(cursor DialogCursor) error {
+	if cursor.Node == -1 {
+		coll.current = nil
+		coll.path = cursor.Path
+		coll.choice = 0
+		return nil
+	}
+
+	if cursor.Node < 0 || cursor.Node >= len(*coll.tree) {
+		return fmt.Errorf("Cursor points to node %d, but the tree only has %d nodes", cursor.Node, len(*coll.tree))
+	}
+
+	n := nOptions((*coll.tree)[cursor.Node])
+	if cursor.Choice != 0 && (cursor.Choice < 0 || cursor.Choice >= n) {
+		return fmt.Errorf("Cursor highlights answer %d of node %d, which has %d answers", cursor.Choice, cursor.Node, n)
+	}
+
+	coll.current = Link(cursor.Node)
+	coll.path = cursor.Path
+	coll.choice = cursor.Choice
+	coll.resolve()
+	return nil
+}
@@ -0,0 +1,70 @@
+package dialog
+
+import(
+	"encoding/json"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tree := DialogTree{
+		&DialogNode{"Hi", Link(1)},
+		&ChoiceDialogNode{"Pick", []string{"A", "B", "C"}, []*int{Link(2), nil, nil}},
+		&DialogNode{"You picked A", nil},
+	}
+
+	coll := MakeDialogTreeCollector(&tree, nil)
+	coll.SetPath("pick.json")
+	coll.CollectOnce()
+	coll.Highlight(2)
+
+	bytes, err := json.Marshal(coll.Cursor())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor := DialogCursor{}
+	if err = json.Unmarshal(bytes, &cursor); err != nil {
+		t.Fatal(err)
+	}
+	if cursor != (DialogCursor{"pick.json", 1, 2}) {
+		t.Errorf("Unexpected cursor %v", cursor)
+	}
+
+	other := MakeDialogTreeCollector(&tree, nil)
+	if err = other.Restore(cursor); err != nil {
+		t.Fatal(err)
+	}
+	if res := other.Peek(); res == nil || res.Dialog != "Pick" {
+		t.Errorf("Restored collector rests on %v", res)
+	}
+	if other.Cursor() != cursor {
+		t.Errorf("Restored cursor %v differs from %v", other.Cursor(), cursor)
+	}
+
+	other.Choose(0)
+	if res := other.CollectOnce(); res == nil || res.Dialog != "You picked A" {
+		t.Errorf("Restored collector continued to %v", res)
+	}
+	if other.Cursor() != (DialogCursor{"pick.json", -1, 0}) {
+		t.Errorf("Ended dialog has cursor %v", other.Cursor())
+	}
+}
+
+func TestCursorRestoreErrors(t *testing.T) {
+	tree := DialogTree{
+		&BinaryDialogNode{"Sure?", nil, nil},
+	}
+
+	cursors := []DialogCursor{
+		{"", 1, 0},
+		{"", -2, 0},
+		{"", 0, 2},
+	}
+
+	for _, cursor := range cursors {
+		coll := MakeDialogTreeCollector(&tree, nil)
+		if err := coll.Restore(cursor); err == nil {
+			t.Errorf("Cursor %v should not be restorable", cursor)
+		}
+	}
+}
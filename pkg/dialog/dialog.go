@@ -4,10 +4,18 @@ import(
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"strings"
 )
 
 const(
 	MaxLetters = 44
+	// Width in pixels of the area text is typed into
+	TextWidth = 228
+	// Width in pixels an option of a choice menu may take up, which keeps
+	// the menu within the width of the dialog window it rests on
+	ChoiceWidth = 230
+	LinesPerPage = 2
+	FontSize = 16
 )
 
 type NodeId int
@@ -17,6 +25,8 @@ const(
 	BinaryDialogNodeId
 	ChoiceDialogNodeId
 	EffectDialogNodeId
+	DialogBranchNodeId
+	DialogAssignNodeId
 )
 
 type DialogTreeVisitor interface {
@@ -24,6 +34,8 @@ type DialogTreeVisitor interface {
 	VisitBinary(*BinaryDialogNode)
 	VisitChoice(*ChoiceDialogNode)
 	VisitEffect(*EffectDialogNode)
+	VisitBranch(*DialogBranchNode)
+	VisitAssign(*DialogAssignNode)
 }
 
 type DialogNodeInterface interface {
@@ -36,23 +48,32 @@ type BinaryDialogNode struct {
 	True, False *int
 }
 
+// YesNoChoices are the answers to a BinaryDialogNode, referencing the string
+// table
+var YesNoChoices = []string{"$ui.yes", "$ui.no"}
+
 type ChoiceDialogNode struct {
 	Dialog string
 	Choices []string
 	Results []*int
 }
 
+// DialogBranchNode compares the flag named by Value1 against the literal
+// Value2 and continues with True or False depending on the outcome
 type DialogBranchNode struct {
 	Value1, Value2 string
 	Operation string
 	True, False *int
 }
 
+// EffectDialogNode asks the game to perform an effect, written as a name
+// followed by space separated arguments, such as "give_item potion 2"
 type EffectDialogNode struct {
 	Effect string
 	Next *int
 }
 
+// DialogAssignNode sets the flag named by Set to the literal To
 type DialogAssignNode struct {
 	Set, To string
 	Next *int
@@ -67,6 +88,15 @@ func Link(index int) *int {
 	return &index
 }
 
+// ParseEffect splits an effect string into its name and arguments
+func ParseEffect(effect string) (string, []string) {
+	fields := strings.Fields(effect)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
 func (d *DialogNode) Visit(visitor DialogTreeVisitor) {
 	visitor.VisitDialog(d)
 }
@@ -99,6 +129,22 @@ func (e *EffectDialogNode) GetNodeId() NodeId {
 	return EffectDialogNodeId
 }
 
+func (b *DialogBranchNode) Visit(visitor DialogTreeVisitor) {
+	visitor.VisitBranch(b)
+}
+
+func (b *DialogBranchNode) GetNodeId() NodeId {
+	return DialogBranchNodeId
+}
+
+func (a *DialogAssignNode) Visit(visitor DialogTreeVisitor) {
+	visitor.VisitAssign(a)
+}
+
+func (a *DialogAssignNode) GetNodeId() NodeId {
+	return DialogAssignNodeId
+}
+
 type DialogTree []DialogNodeInterface
 
 type DialogTreeNodeData struct {
@@ -112,6 +158,12 @@ type BinaryDialogNodeData BinaryDialogNode
 
 type ChoiceDialogNodeData ChoiceDialogNode
 
+type EffectDialogNodeData EffectDialogNode
+
+type DialogBranchNodeData DialogBranchNode
+
+type DialogAssignNodeData DialogAssignNode
+
 type DialogTreeData []DialogTreeNodeData
 
 func (d *DialogNode) MarshalJSON() ([]byte, error) {
@@ -173,6 +225,67 @@ func (m *ChoiceDialogNode) MarshalJSON() ([]byte, error) {
 	return json.Marshal(data)
 }
 
+func (e *EffectDialogNode) MarshalJSON() ([]byte, error) {
+	diag := EffectDialogNodeData{
+		e.Effect,
+		e.Next,
+	}
+
+	bytes, err := json.Marshal(diag)
+	if err != nil {
+		return nil, err
+	}
+
+	data := DialogTreeNodeData{
+		"Effect",
+		bytes,
+	}
+
+	return json.Marshal(data)
+}
+
+func (b *DialogBranchNode) MarshalJSON() ([]byte, error) {
+	diag := DialogBranchNodeData{
+		b.Value1,
+		b.Value2,
+		b.Operation,
+		b.True,
+		b.False,
+	}
+
+	bytes, err := json.Marshal(diag)
+	if err != nil {
+		return nil, err
+	}
+
+	data := DialogTreeNodeData{
+		"Branch",
+		bytes,
+	}
+
+	return json.Marshal(data)
+}
+
+func (a *DialogAssignNode) MarshalJSON() ([]byte, error) {
+	diag := DialogAssignNodeData{
+		a.Set,
+		a.To,
+		a.Next,
+	}
+
+	bytes, err := json.Marshal(diag)
+	if err != nil {
+		return nil, err
+	}
+
+	data := DialogTreeNodeData{
+		"Assign",
+		bytes,
+	}
+
+	return json.Marshal(data)
+}
+
 func (dt *DialogTree) UnmarshalJSON(bytes []byte) error {
 	intermediate := make(DialogTreeData, 0)
 	err := json.Unmarshal(bytes, &intermediate)
@@ -197,6 +310,18 @@ func (dt *DialogTree) UnmarshalJSON(bytes []byte) error {
 			choice := &ChoiceDialogNode{}
 			err = json.Unmarshal(s.Data, choice)
 			node = choice
+		case "Effect":
+			effect := &EffectDialogNode{}
+			err = json.Unmarshal(s.Data, effect)
+			node = effect
+		case "Branch":
+			branch := &DialogBranchNode{}
+			err = json.Unmarshal(s.Data, branch)
+			node = branch
+		case "Assign":
+			assign := &DialogAssignNode{}
+			err = json.Unmarshal(s.Data, assign)
+			node = assign
 		default:
 			return errors.New("Unrecognized node type, " + s.Type)
 		}
@@ -0,0 +1,54 @@
+package dialog
+
+import(
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDialogTreeRoundTrip(t *testing.T) {
+	tree := DialogTree{
+		&DialogNode{"Hello", Link(1)},
+		&BinaryDialogNode{"Surf?", Link(2), nil},
+		&ChoiceDialogNode{"Pick", []string{"A", "B"}, []*int{Link(3), nil}},
+		&EffectDialogNode{"give_item potion 2", Link(4)},
+		&DialogBranchNode{"badges", "3", OpGreater, Link(5), nil},
+		&DialogAssignNode{"met_grandma", "true", nil},
+	}
+
+	bytes, err := json.Marshal(&tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := DialogTree{}
+	if err = json.Unmarshal(bytes, &other); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tree, other) {
+		t.Errorf("Tree did not survive a round trip through JSON: %s", bytes)
+	}
+}
+
+func TestParseEffect(t *testing.T) {
+	type parseEffectTest struct {
+		In string
+		Name string
+		Args []string
+	}
+
+	tests := []parseEffectTest{
+		{"surf", "surf", []string{}},
+		{"give_item potion 2", "give_item", []string{"potion", "2"}},
+		{"  warp   cave.json 3 ", "warp", []string{"cave.json", "3"}},
+		{"", "", nil},
+	}
+
+	for _, test := range tests {
+		name, args := ParseEffect(test.In)
+		if name != test.Name || !reflect.DeepEqual(args, test.Args) {
+			t.Errorf("Parsing %q gave %q %q, expected %q %q", test.In, name, args, test.Name, test.Args)
+		}
+	}
+}
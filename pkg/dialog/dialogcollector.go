@@ -1,49 +1,137 @@
 package dialog
 
+// Upper bound on how many branch and assign nodes may be passed through
+// without reaching a node the caller has to act upon
+const maxResolveSteps = 1024
+
 type DialogTreeCollector struct {
 	tree *DialogTree
+	flags Flags
 	current *int
 	nextResult *DialogTreeCollectorResult
+	path string
+	choice int
 }
 
 type DialogTreeCollectorResult struct {
 	Dialog string
 	NodeId NodeId
 	Opt string
+	Choices []string
 }
 
-func MakeDialogTreeCollector(tree *DialogTree) DialogTreeCollector {
+// MakeDialogTreeCollector walks tree from its first node, reading and writing
+// story variables in flags. A nil flags is replaced with an empty set.
+func MakeDialogTreeCollector(tree *DialogTree, flags Flags) DialogTreeCollector {
+	if flags == nil {
+		flags = make(Flags)
+	}
+
 	if len(*tree) == 0 {
 		return DialogTreeCollector{
 			tree,
+			flags,
 			nil,
 			nil,
+			"",
+			0,
 		}
 	}
 
-	return DialogTreeCollector{
+	coll := DialogTreeCollector{
 		tree,
+		flags,
 		new(int),
 		nil,
+		"",
+		0,
 	}
+	coll.resolve()
+	return coll
 }
 
 func (coll *DialogTreeCollector) CollectOnce() *DialogTreeCollectorResult {
-	if coll.current == nil {
-		return nil
-	}
-	coll.nextResult = &DialogTreeCollectorResult{}
-	(*coll.tree)[*coll.current].Visit(coll)
-	return coll.nextResult
+	coll.choice = 0
+	res := coll.visitCurrent()
+	coll.resolve()
+	return res
 }
 
 func (coll *DialogTreeCollector) Peek() *DialogTreeCollectorResult {
 	old := coll.current
-	res := coll.CollectOnce()
+	res := coll.visitCurrent()
 	coll.current = old
 	return res
 }
 
+// Choose follows the result with the given index out of the node currently
+// waiting for an answer. Choices outside of the result range end the dialog.
+// Binary nodes treat index 0 as yes and anything else as no.
+func (coll *DialogTreeCollector) Choose(index int) {
+	coll.choice = 0
+	node := coll.node()
+	if node == nil {
+		return
+	}
+
+	switch node := node.(type) {
+		case *BinaryDialogNode:
+			if index == 0 {
+				coll.current = node.True
+			} else {
+				coll.current = node.False
+			}
+		case *ChoiceDialogNode:
+			if index < 0 || index >= len(node.Results) {
+				coll.current = nil
+				return
+			}
+			coll.current = node.Results[index]
+	}
+
+	coll.resolve()
+}
+
+func (coll *DialogTreeCollector) node() DialogNodeInterface {
+	if coll.current == nil || *coll.current < 0 || *coll.current >= len(*coll.tree) {
+		coll.current = nil
+		return nil
+	}
+	return (*coll.tree)[*coll.current]
+}
+
+func (coll *DialogTreeCollector) visitCurrent() *DialogTreeCollectorResult {
+	node := coll.node()
+	if node == nil {
+		return nil
+	}
+	coll.nextResult = &DialogTreeCollectorResult{}
+	node.Visit(coll)
+	return coll.nextResult
+}
+
+// resolve passes through any branch and assign nodes under the cursor so that
+// it always rests on a node the caller has to act upon
+func (coll *DialogTreeCollector) resolve() {
+	for steps := 0; steps < maxResolveSteps; steps++ {
+		node := coll.node()
+		if node == nil {
+			return
+		}
+
+		switch node.GetNodeId() {
+			case DialogBranchNodeId, DialogAssignNodeId:
+				coll.nextResult = &DialogTreeCollectorResult{}
+				node.Visit(coll)
+			default:
+				return
+		}
+	}
+
+	// Stuck in a loop without anything to show, give up on the dialog
+	coll.current = nil
+}
+
 func (coll *DialogTreeCollector) VisitDialog(d *DialogNode) {
 	coll.nextResult.Dialog = d.Dialog
 	coll.nextResult.NodeId = d.GetNodeId()
@@ -56,6 +144,9 @@ func (coll *DialogTreeCollector) VisitBinary(b *BinaryDialogNode) {
 }
 
 func (coll *DialogTreeCollector) VisitChoice(c *ChoiceDialogNode) {
+	coll.nextResult.Dialog = c.Dialog
+	coll.nextResult.NodeId = c.GetNodeId()
+	coll.nextResult.Choices = c.Choices
 }
 
 func (coll *DialogTreeCollector) VisitEffect(e *EffectDialogNode) {
@@ -63,3 +154,18 @@ func (coll *DialogTreeCollector) VisitEffect(e *EffectDialogNode) {
 	coll.nextResult.Opt = e.Effect
 	coll.current = e.Next
 }
+
+func (coll *DialogTreeCollector) VisitBranch(b *DialogBranchNode) {
+	coll.nextResult.NodeId = b.GetNodeId()
+	if coll.flags.Compare(b.Value1, b.Operation, b.Value2) {
+		coll.current = b.True
+	} else {
+		coll.current = b.False
+	}
+}
+
+func (coll *DialogTreeCollector) VisitAssign(a *DialogAssignNode) {
+	coll.nextResult.NodeId = a.GetNodeId()
+	coll.flags.Set(a.Set, a.To)
+	coll.current = a.Next
+}
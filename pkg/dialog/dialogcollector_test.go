@@ -0,0 +1,85 @@
+package dialog
+
+import "testing"
+
+func TestCollectorChoose(t *testing.T) {
+	tree := DialogTree{
+		&ChoiceDialogNode{
+			Dialog: "Which one?",
+			Choices: []string{"Left", "Right", "Neither"},
+			Results: []*int{Link(1), Link(2), nil},
+		},
+		&DialogNode{
+			Dialog: "Left it is",
+			Next: nil,
+		},
+		&DialogNode{
+			Dialog: "Right it is",
+			Next: nil,
+		},
+	}
+
+	type chooseTest struct {
+		Choice int
+		Want string
+		WantEnd bool
+	}
+
+	tests := []chooseTest{
+		{0, "Left it is", false},
+		{1, "Right it is", false},
+		{2, "", true},
+		{3, "", true},
+	}
+
+	for _, test := range tests {
+		coll := MakeDialogTreeCollector(&tree, nil)
+		res := coll.Peek()
+		if res == nil || res.NodeId != ChoiceDialogNodeId || len(res.Choices) != 3 {
+			t.Fatalf("Expected choice node to be collected first, got %v", res)
+		}
+
+		coll.Choose(test.Choice)
+		res = coll.Peek()
+		if test.WantEnd {
+			if res != nil {
+				t.Errorf("Choice %d should end the dialog, but got %q", test.Choice, res.Dialog)
+			}
+			continue
+		}
+
+		if res == nil || res.Dialog != test.Want {
+			t.Errorf("Choice %d should lead to %q, but got %v", test.Choice, test.Want, res)
+		}
+	}
+}
+
+func TestCollectorChooseBinary(t *testing.T) {
+	tree := DialogTree{
+		&BinaryDialogNode{
+			Dialog: "Well?",
+			True: Link(1),
+			False: nil,
+		},
+		&EffectDialogNode{
+			Effect: "surf",
+			Next: nil,
+		},
+	}
+
+	coll := MakeDialogTreeCollector(&tree, nil)
+	if res := coll.Peek(); res == nil || res.NodeId != BinaryDialogNodeId {
+		t.Fatalf("Expected binary node to be collected first, got %v", res)
+	}
+
+	coll.Choose(0)
+	if res := coll.Peek(); res == nil || res.NodeId != EffectDialogNodeId || res.Opt != "surf" {
+		t.Errorf("Answering yes should lead to the surf effect, got %v", res)
+	}
+
+	coll = MakeDialogTreeCollector(&tree, nil)
+	coll.Choose(1)
+	if res := coll.Peek(); res != nil {
+		t.Errorf("Answering no should end the dialog, got %v", res)
+	}
+}
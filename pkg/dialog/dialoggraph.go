@@ -0,0 +1,211 @@
+package dialog
+
+import(
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const(
+	GraphDot = "dot"
+	GraphMermaid = "mermaid"
+)
+
+const(
+	shapeBox = iota
+	shapeDiamond
+	shapeHexagon
+	shapeParallelogram
+	shapeRounded
+	shapeEnd
+	shapeMissing
+)
+
+type graphNode struct {
+	name string
+	label string
+	shape int
+}
+
+type graphEdge struct {
+	from string
+	to string
+	label string
+}
+
+// DialogTreeGrapher exports a dialog tree as a graph, drawing every node once
+// so that shared nodes and loops show up as merging edges
+type DialogTreeGrapher struct {
+	tree *DialogTree
+	current int
+	nodes []graphNode
+	edges []graphEdge
+	missing map[int]bool
+	hasEnd bool
+}
+
+func IsValidGraphFormat(format string) bool {
+	return format == GraphDot || format == GraphMermaid
+}
+
+func (g *DialogTreeGrapher) Graph(tree *DialogTree, format string) (string, error) {
+	g.tree = tree
+	g.nodes = make([]graphNode, 0, len(*tree))
+	g.edges = make([]graphEdge, 0, len(*tree))
+	g.missing = make(map[int]bool)
+	g.hasEnd = false
+
+	for i, node := range *tree {
+		g.current = i
+		node.Visit(g)
+	}
+
+	missing := make([]int, 0, len(g.missing))
+	for i := range g.missing {
+		missing = append(missing, i)
+	}
+	sort.Ints(missing)
+	for _, i := range missing {
+		g.nodes = append(g.nodes, graphNode{nodeName(i), "missing node " + strconv.Itoa(i), shapeMissing})
+	}
+	if g.hasEnd {
+		g.nodes = append(g.nodes, graphNode{"stop", "end", shapeEnd})
+	}
+
+	switch format {
+		case GraphDot:
+			return g.dot(), nil
+		case GraphMermaid:
+			return g.mermaid(), nil
+	}
+	return "", fmt.Errorf("Unknown graph format %q", format)
+}
+
+// nodeName names the node at index, where a minus sign would not make a valid
+// identifier for either format
+func nodeName(index int) string {
+	if index < 0 {
+		return "nm" + strconv.Itoa(-index)
+	}
+	return "n" + strconv.Itoa(index)
+}
+
+func (g *DialogTreeGrapher) node(kind string, text string, shape int) {
+	label := strconv.Itoa(g.current) + ": " + kind
+	if text = normalizeText(text); text != "" {
+		label += "\n" + text
+	}
+	g.nodes = append(g.nodes, graphNode{nodeName(g.current), label, shape})
+}
+
+func (g *DialogTreeGrapher) edge(next *int, label string) {
+	to := "stop"
+	if next == nil {
+		g.hasEnd = true
+	} else {
+		to = nodeName(*next)
+		if *next < 0 || *next >= len(*g.tree) {
+			g.missing[*next] = true
+		}
+	}
+	g.edges = append(g.edges, graphEdge{nodeName(g.current), to, label})
+}
+
+func (g *DialogTreeGrapher) dot() string {
+	escape := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+	shapes := map[int]string{
+		shapeBox: "shape=box",
+		shapeDiamond: "shape=diamond",
+		shapeHexagon: "shape=hexagon",
+		shapeParallelogram: "shape=parallelogram",
+		shapeRounded: "shape=box, style=rounded",
+		shapeEnd: "shape=doublecircle",
+		shapeMissing: "shape=box, style=dashed, color=red",
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("digraph dialog {\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&builder, "\t%s [%s, label=\"%s\"];\n", n.name, shapes[n.shape], escape.Replace(n.label))
+	}
+	for _, e := range g.edges {
+		if e.label == "" {
+			fmt.Fprintf(&builder, "\t%s -> %s;\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&builder, "\t%s -> %s [label=\"%s\"];\n", e.from, e.to, escape.Replace(e.label))
+		}
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func (g *DialogTreeGrapher) mermaid() string {
+	escape := strings.NewReplacer("\"", "#quot;", "\n", "<br/>")
+	shapes := map[int][2]string{
+		shapeBox: {"[", "]"},
+		shapeDiamond: {"{", "}"},
+		shapeHexagon: {"{{", "}}"},
+		shapeParallelogram: {"[/", "/]"},
+		shapeRounded: {"(", ")"},
+		shapeEnd: {"((", "))"},
+		shapeMissing: {"[", "]"},
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("flowchart TD\n")
+	for _, n := range g.nodes {
+		shape := shapes[n.shape]
+		fmt.Fprintf(&builder, "\t%s%s\"%s\"%s\n", n.name, shape[0], escape.Replace(n.label), shape[1])
+	}
+	for _, e := range g.edges {
+		if e.label == "" {
+			fmt.Fprintf(&builder, "\t%s --> %s\n", e.from, e.to)
+		} else {
+			fmt.Fprintf(&builder, "\t%s -->|\"%s\"| %s\n", e.from, escape.Replace(e.label), e.to)
+		}
+	}
+	return builder.String()
+}
+
+func (g *DialogTreeGrapher) VisitDialog(d *DialogNode) {
+	g.node("Dialog", d.Dialog, shapeBox)
+	g.edge(d.Next, "")
+}
+
+func (g *DialogTreeGrapher) VisitBinary(b *BinaryDialogNode) {
+	g.node("Binary", b.Dialog, shapeDiamond)
+	g.edge(b.True, "YES")
+	g.edge(b.False, "NO")
+}
+
+func (g *DialogTreeGrapher) VisitChoice(c *ChoiceDialogNode) {
+	g.node("Choice", c.Dialog, shapeHexagon)
+	for i, result := range c.Results {
+		label := strconv.Itoa(i)
+		if i < len(c.Choices) {
+			label = c.Choices[i]
+		}
+		g.edge(result, label)
+	}
+}
+
+func (g *DialogTreeGrapher) VisitEffect(e *EffectDialogNode) {
+	g.node("Effect", e.Effect, shapeParallelogram)
+	g.edge(e.Next, "")
+}
+
+func (g *DialogTreeGrapher) VisitBranch(b *DialogBranchNode) {
+	condition := b.Value1 + " " + b.Operation + " " + b.Value2
+	if b.Operation == OpHas {
+		condition = "has " + b.Value1
+	}
+	g.node("Branch", condition, shapeDiamond)
+	g.edge(b.True, "true")
+	g.edge(b.False, "false")
+}
+
+func (g *DialogTreeGrapher) VisitAssign(a *DialogAssignNode) {
+	g.node("Assign", a.Set + " = " + a.To, shapeRounded)
+	g.edge(a.Next, "")
+}
@@ -0,0 +1,61 @@
+package dialog
+
+import(
+	"strings"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	tree := DialogTree{
+		&ChoiceDialogNode{Dialog: "Pick \"one\"", Choices: []string{"RED", "BLUE"}, Results: []*int{Link(1), Link(1)}},
+		&DialogBranchNode{Value1: "badges", Operation: OpLess, Value2: "8", True: Link(2), False: Link(5)},
+		&DialogNode{Dialog: "Come back\nlater.", Next: nil},
+		&DialogNode{Dialog: "Lost", Next: Link(-1)},
+	}
+
+	type graphTest struct {
+		Format string
+		Want []string
+	}
+
+	tests := []graphTest{
+		{GraphDot, []string{
+			"digraph dialog {",
+			`n0 [shape=hexagon, label="0: Choice\nPick \"one\""];`,
+			`n0 -> n1 [label="RED"];`,
+			`n0 -> n1 [label="BLUE"];`,
+			`n1 -> n2 [label="true"];`,
+			`n5 [shape=box, style=dashed, color=red, label="missing node 5"];`,
+			`n2 -> stop;`,
+			`n3 -> nm1;`,
+			`nm1 [shape=box, style=dashed, color=red, label="missing node -1"];`,
+		}},
+		{GraphMermaid, []string{
+			"flowchart TD",
+			`n0{{"0: Choice<br/>Pick #quot;one#quot;"}}`,
+			`n0 -->|"BLUE"| n1`,
+			`n1{"1: Branch<br/>badges < 8"}`,
+			`n2["2: Dialog<br/>Come back<br/>later."]`,
+			`stop(("end"))`,
+			`n3 --> nm1`,
+		}},
+	}
+
+	for _, test := range tests {
+		grapher := DialogTreeGrapher{}
+		got, err := grapher.Graph(&tree, test.Format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range test.Want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s graph is missing %q:\n%s", test.Format, want, got)
+			}
+		}
+	}
+
+	grapher := DialogTreeGrapher{}
+	if _, err := grapher.Graph(&tree, "svg"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
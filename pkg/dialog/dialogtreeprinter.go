@@ -7,11 +7,13 @@ import(
 type DialogTreePrinter struct {
 	depth uint
 	tree *DialogTree
+	printed []bool
 }
 
 func (p *DialogTreePrinter) Print(tree *DialogTree) {
 	p.tree = tree
 	p.depth = 0
+	p.printed = make([]bool, len(*tree))
 
 	if len(*p.tree) < 1 {
 		return
@@ -26,7 +28,22 @@ func (p *DialogTreePrinter) pad() {
 	}
 }
 
+// visit prints the node at index, or a reference to it if it has already
+// been printed, so that shared nodes and loops are only printed once
 func (p *DialogTreePrinter) visit(index int) {
+	if index < 0 || index >= len(*p.tree) {
+		p.pad()
+		fmt.Println("-> invalid node", index)
+		return
+	}
+
+	if p.printed[index] {
+		p.pad()
+		fmt.Println("-> node", index)
+		return
+	}
+
+	p.printed[index] = true
 	(*p.tree)[index].Visit(p)
 }
 
@@ -86,3 +103,30 @@ func (p *DialogTreePrinter) VisitEffect(e *EffectDialogNode) {
 		p.depth--
 	}
 }
+
+func (p *DialogTreePrinter) VisitBranch(b *DialogBranchNode) {
+	p.pad()
+	fmt.Println("Branch:", b.Value1, b.Operation, b.Value2)
+	p.depth++
+	if b.True != nil {
+		p.visit(*b.True)
+	} else {
+		p.end()
+	}
+	if b.False != nil {
+		p.visit(*b.False)
+	} else {
+		p.end()
+	}
+	p.depth--
+}
+
+func (p *DialogTreePrinter) VisitAssign(a *DialogAssignNode) {
+	p.pad()
+	fmt.Println("Assign:", a.Set, "=", a.To)
+	if a.Next != nil {
+		p.depth++
+		p.visit(*a.Next)
+		p.depth--
+	}
+}
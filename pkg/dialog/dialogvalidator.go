@@ -0,0 +1,312 @@
+package dialog
+
+import(
+	"fmt"
+	"github.com/atemmel/pok/pkg/locale"
+	"golang.org/x/image/font"
+	"sort"
+)
+
+// Kinds of issues reported by DialogTreeValidator
+const(
+	IssueBadLink = "bad-link"
+	IssueUnreachable = "unreachable"
+	IssueInfiniteLoop = "infinite-loop"
+	IssueChoiceMismatch = "choice-mismatch"
+	IssueLongLine = "long-line"
+	IssueBadOperation = "bad-operation"
+	IssueBadMarkup = "bad-markup"
+)
+
+type Issue struct {
+	Node int
+	Kind string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("node %d: %s: %s", i.Node, i.Kind, i.Message)
+}
+
+// DialogTreeValidator looks for mistakes in a dialog tree without running it.
+// Text is measured with Face, or counted in letters if Face is nil. Texts
+// referencing a key are measured as written in each of Tables, which maps
+// locales to their string tables.
+type DialogTreeValidator struct {
+	Face font.Face
+	Tables map[string]locale.Table
+	tree *DialogTree
+	index int
+	issues []Issue
+	edges [][]int
+	ends []bool
+}
+
+func (v *DialogTreeValidator) Validate(tree *DialogTree) []Issue {
+	v.tree = tree
+	v.issues = make([]Issue, 0)
+	v.edges = make([][]int, len(*tree))
+	v.ends = make([]bool, len(*tree))
+
+	for v.index = range *tree {
+		(*tree)[v.index].Visit(v)
+	}
+
+	if len(*tree) > 0 {
+		reachable := v.reachable()
+		v.checkUnreachable(reachable)
+		v.checkEndless(reachable)
+		v.checkSilentLoops()
+	}
+
+	return v.issues
+}
+
+func (v *DialogTreeValidator) report(node int, kind string, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		node,
+		kind,
+		fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *DialogTreeValidator) link(name string, ptr *int) {
+	if ptr == nil {
+		v.ends[v.index] = true
+		return
+	}
+
+	if *ptr < 0 || *ptr >= len(*v.tree) {
+		v.report(v.index, IssueBadLink, "%s links to %d, but the tree only has %d nodes", name, *ptr, len(*v.tree))
+		v.ends[v.index] = true
+		return
+	}
+
+	v.edges[v.index] = append(v.edges[v.index], *ptr)
+}
+
+// lines checks the markup of str and that every word fits on a line of its
+// own once the markup has been stripped, counting placeholders as written.
+// References are checked in every locale instead.
+func (v *DialogTreeValidator) lines(str string) {
+	v.inLocales(str, v.measure)
+}
+
+// option checks that the choice str fits in the choice menu, in every locale
+// if it is a reference
+func (v *DialogTreeValidator) option(str string) {
+	v.inLocales(str, v.measureOption)
+}
+
+// inLocales calls check with str, or with the text str references in every
+// locale along with where it was found
+func (v *DialogTreeValidator) inLocales(str string, check func(str, where string)) {
+	key, ok := locale.Key(str)
+	if !ok {
+		check(str, "")
+		return
+	}
+
+	locales := make([]string, 0, len(v.Tables))
+	for l := range v.Tables {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	for _, l := range locales {
+		if text := v.Tables[l][key]; text != "" {
+			check(text, " in " + l)
+		}
+	}
+}
+
+func (v *DialogTreeValidator) measureOption(str string, where string) {
+	width := ChoiceWidth
+	if v.Face == nil {
+		width = MaxLetters
+	}
+
+	if measure(v.Face, []rune(str)) > width {
+		v.report(v.index, IssueLongLine, "option %q is too wide to fit in the choice menu%s", str, where)
+	}
+}
+
+func (v *DialogTreeValidator) measure(str string, where string) {
+	markup, err := ParseMarkup(str, nil)
+	if err != nil {
+		v.report(v.index, IssueBadMarkup, "%s%s", err.Error(), where)
+		return
+	}
+
+	width := TextWidth
+	if v.Face == nil {
+		width = MaxLetters
+	}
+
+	for _, word := range LongWords(v.Face, markup.String(), width) {
+		v.report(v.index, IssueLongLine, "%q is too wide to fit on a line%s", word, where)
+	}
+}
+
+// reachable marks every node that can be reached from the first one
+func (v *DialogTreeValidator) reachable() []bool {
+	seen := make([]bool, len(*v.tree))
+	seen[0] = true
+	queue := []int{0}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range v.edges[current] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return seen
+}
+
+func (v *DialogTreeValidator) checkUnreachable(reachable []bool) {
+	for i := range reachable {
+		if !reachable[i] {
+			v.report(i, IssueUnreachable, "node can never be reached")
+		}
+	}
+}
+
+// checkEndless reports the nodes through which the dialog enters a part of
+// the tree it can never leave
+func (v *DialogTreeValidator) checkEndless(reachable []bool) {
+	canEnd := make([]bool, len(*v.tree))
+	reverse := make([][]int, len(*v.tree))
+	queue := make([]int, 0)
+
+	for i := range v.edges {
+		for _, next := range v.edges[i] {
+			reverse[next] = append(reverse[next], i)
+		}
+		if v.ends[i] {
+			canEnd[i] = true
+			queue = append(queue, i)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, prev := range reverse[current] {
+			if !canEnd[prev] {
+				canEnd[prev] = true
+				queue = append(queue, prev)
+			}
+		}
+	}
+
+	reported := make([]bool, len(*v.tree))
+	trap := func(i int) {
+		if !reported[i] {
+			reported[i] = true
+			v.report(i, IssueInfiniteLoop, "dialog can never end once this node is reached")
+		}
+	}
+
+	if !canEnd[0] {
+		trap(0)
+	}
+
+	for i := range v.edges {
+		if !reachable[i] || !canEnd[i] {
+			continue
+		}
+		for _, next := range v.edges[i] {
+			if !canEnd[next] {
+				trap(next)
+			}
+		}
+	}
+}
+
+func isSilent(node DialogNodeInterface) bool {
+	id := node.GetNodeId()
+	return id == DialogBranchNodeId || id == DialogAssignNodeId
+}
+
+// checkSilentLoops reports loops made only out of branch and assign nodes,
+// which would spin forever without showing the player anything
+func (v *DialogTreeValidator) checkSilentLoops() {
+	const(
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(*v.tree))
+
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		for _, next := range v.edges[i] {
+			if !isSilent((*v.tree)[next]) {
+				continue
+			}
+			if state[next] == visiting {
+				v.report(next, IssueInfiniteLoop, "loop of branch and assign nodes never shows any dialog")
+			} else if state[next] == unvisited {
+				visit(next)
+			}
+		}
+		state[i] = visited
+	}
+
+	for i := range *v.tree {
+		if state[i] == unvisited && isSilent((*v.tree)[i]) {
+			visit(i)
+		}
+	}
+}
+
+func (v *DialogTreeValidator) VisitDialog(d *DialogNode) {
+	v.lines(d.Dialog)
+	v.link("Next", d.Next)
+}
+
+func (v *DialogTreeValidator) VisitBinary(b *BinaryDialogNode) {
+	v.lines(b.Dialog)
+	v.link("True", b.True)
+	v.link("False", b.False)
+}
+
+func (v *DialogTreeValidator) VisitChoice(c *ChoiceDialogNode) {
+	v.lines(c.Dialog)
+	if len(c.Choices) != len(c.Results) {
+		v.report(v.index, IssueChoiceMismatch, "%d choices but %d results", len(c.Choices), len(c.Results))
+	} else if len(c.Choices) == 0 {
+		v.report(v.index, IssueChoiceMismatch, "node offers no choices")
+	}
+	for _, choice := range c.Choices {
+		v.option(choice)
+	}
+	for i := range c.Results {
+		v.link(fmt.Sprintf("Results[%d]", i), c.Results[i])
+	}
+}
+
+func (v *DialogTreeValidator) VisitEffect(e *EffectDialogNode) {
+	v.link("Next", e.Next)
+}
+
+func (v *DialogTreeValidator) VisitBranch(b *DialogBranchNode) {
+	if !IsValidOperation(b.Operation) {
+		v.report(v.index, IssueBadOperation, "unknown operation \"%s\"", b.Operation)
+	}
+	v.link("True", b.True)
+	v.link("False", b.False)
+}
+
+func (v *DialogTreeValidator) VisitAssign(a *DialogAssignNode) {
+	if a.Set == "" {
+		v.report(v.index, IssueBadOperation, "assignment to a flag without a name")
+	}
+	v.link("Next", a.Next)
+}
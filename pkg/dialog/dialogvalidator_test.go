@@ -0,0 +1,85 @@
+package dialog
+
+import(
+	"github.com/atemmel/pok/pkg/locale"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	type validatorTest struct {
+		Name string
+		Tree DialogTree
+		Want []string
+	}
+
+	tests := []validatorTest{
+		{"clean", DialogTree{
+			&BinaryDialogNode{"Surf?", Link(1), nil},
+			&EffectDialogNode{"surf", nil},
+		}, []string{}},
+		{"bad link", DialogTree{
+			&DialogNode{"Hi", Link(3)},
+		}, []string{IssueBadLink}},
+		{"unreachable", DialogTree{
+			&DialogNode{"Hi", nil},
+			&DialogNode{"Bye", nil},
+		}, []string{IssueUnreachable}},
+		{"endless", DialogTree{
+			&DialogNode{"Hi", Link(1)},
+			&DialogNode{"Again", Link(1)},
+		}, []string{IssueInfiniteLoop}},
+		{"silent loop", DialogTree{
+			&DialogAssignNode{"a", "b", Link(1)},
+			&DialogBranchNode{"a", "b", OpEqual, Link(0), nil},
+		}, []string{IssueInfiniteLoop}},
+		{"mismatch", DialogTree{
+			&ChoiceDialogNode{"Pick", []string{"A", "B"}, []*int{nil}},
+		}, []string{IssueChoiceMismatch}},
+		{"long line wraps", DialogTree{
+			&DialogNode{"Wrapping fixes this line, which is much too long to fit in the box", nil},
+		}, []string{}},
+		{"long word", DialogTree{
+			&DialogNode{"Llanfairpwllgwyngyllgogerychwyrndrobwllllantysiliogogogoch", nil},
+		}, []string{IssueLongLine}},
+		{"markup fits", DialogTree{
+			&DialogNode{"<color=red>This line fits</color> once<pause=20> stripped", nil},
+		}, []string{}},
+		{"bad markup", DialogTree{
+			&DialogNode{"<color=red>Unclosed", nil},
+		}, []string{IssueBadMarkup}},
+		{"long translation", DialogTree{
+			&DialogNode{"$greeting", nil},
+		}, []string{IssueLongLine}},
+		{"unknown reference", DialogTree{
+			&DialogNode{"$farewell", nil},
+		}, []string{}},
+		{"long option", DialogTree{
+			&ChoiceDialogNode{"Pick", []string{"No", "Yes, and tell me all about it once more please"}, []*int{nil, nil}},
+		}, []string{IssueLongLine}},
+		{"long translated option", DialogTree{
+			&ChoiceDialogNode{"Pick", []string{"$greeting"}, []*int{nil}},
+		}, []string{IssueLongLine}},
+		{"bad operation", DialogTree{
+			&DialogBranchNode{"a", "b", "~=", nil, nil},
+		}, []string{IssueBadOperation}},
+	}
+
+	tables := map[string]locale.Table{
+		"en": {"greeting": "Hello there"},
+		"xx": {"greeting": "Llanfairpwllgwyngyllgogerychwyrndrobwllllantysiliogogogoch"},
+	}
+
+	for _, test := range tests {
+		validator := DialogTreeValidator{Tables: tables}
+		issues := validator.Validate(&test.Tree)
+		if len(issues) != len(test.Want) {
+			t.Errorf("%s: expected %d issues, got %v", test.Name, len(test.Want), issues)
+			continue
+		}
+		for i := range issues {
+			if issues[i].Kind != test.Want[i] {
+				t.Errorf("%s: expected issue %q, got %v", test.Name, test.Want[i], issues[i])
+			}
+		}
+	}
+}
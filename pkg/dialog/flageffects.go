@@ -0,0 +1,39 @@
+package dialog
+
+import(
+	"strconv"
+)
+
+// FlagEffect is an effect which only changes flags, so that the simulator can
+// perform it the same way the game does. NArgs is the number of arguments the
+// effect expects.
+type FlagEffect struct {
+	NArgs int
+	Do func(flags Flags, args []string) error
+}
+
+var FlagEffects = map[string]FlagEffect{
+	"set_flag": {1, setFlagEffect},
+	"give_item": {2, giveItemEffect},
+}
+
+// Items are kept as flags holding the amount until there is an inventory
+func ItemFlag(item string) string {
+	return "item:" + item
+}
+
+func setFlagEffect(flags Flags, args []string) error {
+	flags.Set(args[0], "true")
+	return nil
+}
+
+func giveItemEffect(flags Flags, args []string) error {
+	amount, err := strconv.Atoi(args[1])
+	if err != nil {
+		return err
+	}
+
+	held, _ := strconv.Atoi(flags.Get(ItemFlag(args[0])))
+	flags.Set(ItemFlag(args[0]), strconv.Itoa(held + amount))
+	return nil
+}
@@ -0,0 +1,83 @@
+package dialog
+
+import(
+	"strconv"
+)
+
+// Operations understood by DialogBranchNode
+const(
+	OpEqual = "=="
+	OpNotEqual = "!="
+	OpLess = "<"
+	OpGreater = ">"
+	OpHas = "has"
+)
+
+// Flags holds the story variables dialog trees read and write. Unset flags
+// read as the empty string.
+type Flags map[string]string
+
+func (f Flags) Get(name string) string {
+	return f[name]
+}
+
+func (f Flags) Set(name, value string) {
+	f[name] = value
+}
+
+// Has reports whether a flag has been raised, meaning it is set to anything
+// but the empty string or "false"
+func (f Flags) Has(name string) bool {
+	value, ok := f[name]
+	return ok && value != "" && value != "false"
+}
+
+// Compare evaluates the flag called name against a literal value. Ordering
+// operations compare numerically, where unset flags count as zero.
+func (f Flags) Compare(name, operation, value string) bool {
+	switch operation {
+		case OpEqual:
+			return f.Get(name) == value
+		case OpNotEqual:
+			return f.Get(name) != value
+		case OpLess, OpGreater:
+			lhs, rhs, ok := f.numbers(name, value)
+			if !ok {
+				return false
+			}
+			if operation == OpLess {
+				return lhs < rhs
+			}
+			return lhs > rhs
+		case OpHas:
+			return f.Has(name)
+	}
+	return false
+}
+
+func (f Flags) numbers(name, value string) (int, int, bool) {
+	str := f.Get(name)
+	if str == "" {
+		str = "0"
+	}
+
+	lhs, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	rhs, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return lhs, rhs, true
+}
+
+func IsValidOperation(operation string) bool {
+	switch operation {
+		case OpEqual, OpNotEqual, OpLess, OpGreater, OpHas:
+			return true
+	}
+	return false
+}
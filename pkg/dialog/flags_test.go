@@ -0,0 +1,82 @@
+package dialog
+
+import "testing"
+
+func TestFlagsCompare(t *testing.T) {
+	flags := Flags{
+		"met_grandma": "true",
+		"badges": "3",
+		"rival": "Gary",
+		"declined": "false",
+	}
+
+	type compareTest struct {
+		Name, Operation, Value string
+		Want bool
+	}
+
+	tests := []compareTest{
+		{"rival", OpEqual, "Gary", true},
+		{"rival", OpNotEqual, "Gary", false},
+		{"badges", OpLess, "4", true},
+		{"badges", OpGreater, "4", false},
+		{"unset", OpLess, "1", true},
+		{"rival", OpGreater, "1", false},
+		{"met_grandma", OpHas, "", true},
+		{"declined", OpHas, "", false},
+		{"unset", OpHas, "", false},
+		{"badges", "~", "3", false},
+	}
+
+	for _, test := range tests {
+		if output := flags.Compare(test.Name, test.Operation, test.Value); output != test.Want {
+			t.Errorf("%s %s %s evaluated to %t, expected %t", test.Name, test.Operation, test.Value, output, test.Want)
+		}
+	}
+}
+
+func TestCollectorBranchAndAssign(t *testing.T) {
+	tree := DialogTree{
+		&DialogBranchNode{
+			Value1: "met_grandma",
+			Operation: OpHas,
+			True: Link(3),
+			False: Link(1),
+		},
+		&DialogAssignNode{
+			Set: "met_grandma",
+			To: "true",
+			Next: Link(2),
+		},
+		&DialogNode{
+			Dialog: "Nice to meet you!",
+			Next: nil,
+		},
+		&DialogNode{
+			Dialog: "Welcome back!",
+			Next: nil,
+		},
+	}
+
+	flags := make(Flags)
+	wants := []string{"Nice to meet you!", "Welcome back!"}
+	for _, want := range wants {
+		coll := MakeDialogTreeCollector(&tree, flags)
+		if res := coll.CollectOnce(); res == nil || res.Dialog != want {
+			t.Errorf("Expected %q, got %v", want, res)
+		}
+	}
+
+	loop := DialogTree{
+		&DialogAssignNode{
+			Set: "a",
+			To: "b",
+			Next: Link(0),
+		},
+	}
+
+	coll := MakeDialogTreeCollector(&loop, nil)
+	if res := coll.Peek(); res != nil {
+		t.Errorf("A loop of assignments should end the dialog, got %v", res)
+	}
+}
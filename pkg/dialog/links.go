@@ -0,0 +1,56 @@
+package dialog
+
+// linkCollector gathers pointers to every link out of a node, so that links
+// can be rewritten without caring about the type of node
+type linkCollector struct {
+	links []**int
+}
+
+func nodeLinks(node DialogNodeInterface) []**int {
+	collector := &linkCollector{}
+	node.Visit(collector)
+	return collector.links
+}
+
+// swapNodes swaps the places of two nodes, keeping all links intact
+func swapNodes(tree DialogTree, a, b int) {
+	tree[a], tree[b] = tree[b], tree[a]
+	for _, node := range tree {
+		for _, link := range nodeLinks(node) {
+			if *link == nil {
+				continue
+			}
+			if **link == a {
+				*link = Link(b)
+			} else if **link == b {
+				*link = Link(a)
+			}
+		}
+	}
+}
+
+func (l *linkCollector) VisitDialog(d *DialogNode) {
+	l.links = append(l.links, &d.Next)
+}
+
+func (l *linkCollector) VisitBinary(b *BinaryDialogNode) {
+	l.links = append(l.links, &b.True, &b.False)
+}
+
+func (l *linkCollector) VisitChoice(c *ChoiceDialogNode) {
+	for i := range c.Results {
+		l.links = append(l.links, &c.Results[i])
+	}
+}
+
+func (l *linkCollector) VisitEffect(e *EffectDialogNode) {
+	l.links = append(l.links, &e.Next)
+}
+
+func (l *linkCollector) VisitBranch(b *DialogBranchNode) {
+	l.links = append(l.links, &b.True, &b.False)
+}
+
+func (l *linkCollector) VisitAssign(a *DialogAssignNode) {
+	l.links = append(l.links, &a.Next)
+}
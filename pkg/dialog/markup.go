@@ -0,0 +1,288 @@
+package dialog
+
+import(
+	"fmt"
+	"github.com/atemmel/pok/pkg/locale"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Dialog text may hold markup, which is parsed right before it is shown:
+//
+//	<color=red>...</color>   colors the enclosed text
+//	<speed=fast>...</speed>  types the enclosed text at another speed
+//	<pause=30>               waits 30 frames before typing on
+//	{PLAYER} {RIVAL}         names of the player and the rival
+//	{flag:NAME}              value of the game flag NAME
+//
+// "<<" and "{{" stand for a literal "<" and "{".
+
+const(
+	tagColor = "color"
+	tagSpeed = "speed"
+	tagPause = "pause"
+)
+
+const(
+	VarPlayer = "PLAYER"
+	VarRival = "RIVAL"
+	varFlagPrefix = "flag:"
+)
+
+const MaxPause = 600
+
+var MarkupColors = map[string]color.RGBA{
+	"red": {224, 8, 8, 255},
+	"blue": {48, 80, 200, 255},
+	"green": {32, 152, 8, 255},
+}
+
+// TextSpeeds lists the speeds a span of text may be typed at, from slowest
+// to fastest
+var TextSpeeds = []string{
+	"slow",
+	"normal",
+	"fast",
+	"instant",
+}
+
+// Span is a run of text sharing the same color and speed, where an empty
+// Color or Speed means that of the dialog box
+type Span struct {
+	Text string
+	Color string
+	Speed string
+	Pause int
+}
+
+type Markup []Span
+
+// VarResolver looks up the text a placeholder such as "PLAYER" or
+// "flag:NAME" should be replaced with
+type VarResolver func(name string) string
+
+type MarkupError struct {
+	Offset int
+	Message string
+}
+
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Message)
+}
+
+// String returns the text without any markup
+func (m Markup) String() string {
+	builder := strings.Builder{}
+	for _, span := range m {
+		builder.WriteString(span.Text)
+	}
+	return builder.String()
+}
+
+func IsValidSpeed(speed string) bool {
+	for _, s := range TextSpeeds {
+		if s == speed {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidVar(name string) bool {
+	if name == VarPlayer || name == VarRival {
+		return true
+	}
+	return strings.HasPrefix(name, varFlagPrefix) && isWord(name[len(varFlagPrefix):])
+}
+
+type markupTag struct {
+	name string
+	value string
+	offset int
+}
+
+type markupParser struct {
+	spans Markup
+	stack []markupTag
+	current Span
+	builder strings.Builder
+	// Offset of the last pause, which must be followed by some text
+	pause int
+}
+
+func (p *markupParser) flush() {
+	if p.builder.Len() == 0 && p.current.Pause == 0 {
+		return
+	}
+	p.current.Text = p.builder.String()
+	p.spans = append(p.spans, p.current)
+	p.builder.Reset()
+	p.current.Pause = 0
+}
+
+func (p *markupParser) style() {
+	p.current.Color = ""
+	p.current.Speed = ""
+	for _, t := range p.stack {
+		if t.name == tagColor {
+			p.current.Color = t.value
+		} else {
+			p.current.Speed = t.value
+		}
+	}
+}
+
+func (p *markupParser) tag(body string, offset int) error {
+	if strings.HasPrefix(body, "/") {
+		name := body[1:]
+		if len(p.stack) == 0 {
+			return &MarkupError{offset, fmt.Sprintf("</%s> does not close any tag", name)}
+		}
+		top := p.stack[len(p.stack) - 1]
+		if top.name != name {
+			return &MarkupError{offset, fmt.Sprintf("</%s> closes <%s=%s>", name, top.name, top.value)}
+		}
+		p.flush()
+		p.stack = p.stack[:len(p.stack) - 1]
+		p.style()
+		return nil
+	}
+
+	fields := strings.SplitN(body, "=", 2)
+	if len(fields) != 2 {
+		return &MarkupError{offset, fmt.Sprintf("tag <%s> needs a value", body)}
+	}
+	name, value := fields[0], fields[1]
+
+	switch name {
+		case tagColor:
+			if _, ok := MarkupColors[value]; !ok {
+				return &MarkupError{offset, fmt.Sprintf("unknown color %q", value)}
+			}
+		case tagSpeed:
+			if !IsValidSpeed(value) {
+				return &MarkupError{offset, fmt.Sprintf("unknown speed %q", value)}
+			}
+		case tagPause:
+			frames, err := strconv.Atoi(value)
+			if err != nil || frames < 1 || frames > MaxPause {
+				return &MarkupError{offset, fmt.Sprintf("pause must be between 1 and %d frames, not %q", MaxPause, value)}
+			}
+			p.flush()
+			p.current.Pause += frames
+			p.pause = offset
+			return nil
+		default:
+			return &MarkupError{offset, fmt.Sprintf("unknown tag <%s>", name)}
+	}
+
+	p.flush()
+	p.stack = append(p.stack, markupTag{name, value, offset})
+	p.style()
+	return nil
+}
+
+// ParseMarkup splits str into spans, replacing placeholders with what vars
+// resolves them to. Placeholders are left as they are if vars is nil.
+func ParseMarkup(str string, vars VarResolver) (Markup, error) {
+	p := markupParser{}
+	p.spans = make(Markup, 0, 1)
+
+	for i := 0; i < len(str); {
+		switch {
+			case strings.HasPrefix(str[i:], "<<"):
+				p.builder.WriteByte('<')
+				i += 2
+			case strings.HasPrefix(str[i:], "{{"):
+				p.builder.WriteByte('{')
+				i += 2
+			case str[i] == '<':
+				end := strings.IndexByte(str[i:], '>')
+				if end == -1 {
+					return nil, &MarkupError{i, "tag is never closed with \">\""}
+				}
+				if err := p.tag(str[i + 1:i + end], i); err != nil {
+					return nil, err
+				}
+				i += end + 1
+			case str[i] == '{':
+				end := strings.IndexByte(str[i:], '}')
+				if end == -1 {
+					return nil, &MarkupError{i, "placeholder is never closed with \"}\""}
+				}
+				name := str[i + 1:i + end]
+				if !isValidVar(name) {
+					return nil, &MarkupError{i, fmt.Sprintf("unknown placeholder {%s}", name)}
+				}
+				if vars == nil {
+					p.builder.WriteString(str[i:i + end + 1])
+				} else {
+					p.builder.WriteString(vars(name))
+				}
+				i += end + 1
+			default:
+				p.builder.WriteByte(str[i])
+				i++
+		}
+	}
+
+	if len(p.stack) > 0 {
+		top := p.stack[len(p.stack) - 1]
+		return nil, &MarkupError{top.offset, fmt.Sprintf("<%s=%s> is never closed", top.name, top.value)}
+	}
+
+	p.flush()
+	if !p.typedAfterPause() {
+		return nil, &MarkupError{p.pause, "pause is not followed by any text to wait before"}
+	}
+	return p.spans, nil
+}
+
+// typedAfterPause tells if some text follows the last pause, as a pause is
+// waited for before typing what comes after it
+func (p *markupParser) typedAfterPause() bool {
+	for i := len(p.spans) - 1; i >= 0; i-- {
+		if p.spans[i].Text != "" {
+			return true
+		}
+		if p.spans[i].Pause > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FlagName returns the flag a "flag:NAME" placeholder refers to
+func FlagName(name string) (string, bool) {
+	if strings.HasPrefix(name, varFlagPrefix) {
+		return name[len(varFlagPrefix):], true
+	}
+	return "", false
+}
+
+// ValidateMarkup makes sure the text of every node in the tree parses, as it
+// is shown in the current locale
+func ValidateMarkup(tree *DialogTree) error {
+	for i, node := range *tree {
+		var str string
+		switch n := node.(type) {
+			case *DialogNode:
+				str = n.Dialog
+			case *BinaryDialogNode:
+				str = n.Dialog
+			case *ChoiceDialogNode:
+				str = n.Dialog
+			default:
+				continue
+		}
+		shown := locale.Text(str)
+		if _, err := ParseMarkup(shown, nil); err != nil {
+			if _, ok := locale.Key(str); ok {
+				return fmt.Errorf("Node %d, %s: %s", i, str, err.Error())
+			}
+			return fmt.Errorf("Node %d: %s", i, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package dialog
+
+import(
+	"github.com/atemmel/pok/pkg/locale"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	type markupTest struct {
+		Input string
+		Want Markup
+	}
+
+	vars := func(name string) string {
+		switch name {
+			case VarPlayer:
+				return "BRENDAN"
+			case "flag:badges":
+				return "3"
+		}
+		return ""
+	}
+
+	tests := []markupTest{
+		{"Plain text", Markup{{"Plain text", "", "", 0}}},
+		{"Hi {PLAYER}!", Markup{{"Hi BRENDAN!", "", "", 0}}},
+		{"You have {flag:badges} badges", Markup{{"You have 3 badges", "", "", 0}}},
+		{"A <color=red>red <speed=slow>slow</speed></color> word", Markup{
+			{"A ", "", "", 0},
+			{"red ", "red", "", 0},
+			{"slow", "red", "slow", 0},
+			{" word", "", "", 0},
+		}},
+		{"Wait...<pause=30>done", Markup{
+			{"Wait...", "", "", 0},
+			{"done", "", "", 30},
+		}},
+		{"<<3 {{x}", Markup{{"<3 {x}", "", "", 0}}},
+		{"", Markup{}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseMarkup(test.Input, vars)
+		if err != nil {
+			t.Errorf("%q: %v", test.Input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%q: expected %v, got %v", test.Input, test.Want, got)
+		}
+	}
+}
+
+func TestParseMarkupErrors(t *testing.T) {
+	type errorTest struct {
+		Input string
+		Offset int
+	}
+
+	tests := []errorTest{
+		{"<color=red>open", 0},
+		{"a </color>", 2},
+		{"<color=red><speed=fast>x</color></speed>", 24},
+		{"<color=pink>x</color>", 0},
+		{"<speed=warp>x</speed>", 0},
+		{"<pause=0>", 0},
+		{"<pause=soon>", 0},
+		{"Done<pause=30>", 4},
+		{"<color=red>Done<pause=30></color>", 15},
+		{"<bold=yes>x</bold>", 0},
+		{"<color", 0},
+		{"Hi {PLAYR}", 3},
+		{"Hi {PLAYER", 3},
+		{"{flag:}", 0},
+	}
+
+	for _, test := range tests {
+		_, err := ParseMarkup(test.Input, nil)
+		markupErr, ok := err.(*MarkupError)
+		if !ok {
+			t.Errorf("%q: expected a markup error, got %v", test.Input, err)
+			continue
+		}
+		if markupErr.Offset != test.Offset {
+			t.Errorf("%q: expected error at %d, got %v", test.Input, test.Offset, markupErr)
+		}
+	}
+}
+
+func TestValidateMarkupLocalized(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer locale.Use(filepath.Join(dir, "none"), locale.DefaultLocale)
+
+	table := locale.Table{"ok": "<color=red>Fine</color>", "broken": "<color=red>Oops"}
+	if err = table.Save(locale.Path(dir, locale.DefaultLocale)); err != nil {
+		t.Fatal(err)
+	}
+	if err = locale.Use(dir, locale.DefaultLocale); err != nil {
+		t.Fatal(err)
+	}
+
+	type validateTest struct {
+		Dialog string
+		Valid bool
+	}
+
+	tests := []validateTest{
+		{"$ok", true},
+		{"$broken", false},
+		{"$unknown", true},
+		{"<color=red>Plain", false},
+	}
+
+	for _, test := range tests {
+		tree := DialogTree{&DialogNode{test.Dialog, nil}}
+		if err := ValidateMarkup(&tree); (err == nil) != test.Valid {
+			t.Errorf("%q: expected valid to be %v, got %v", test.Dialog, test.Valid, err)
+		}
+	}
+}
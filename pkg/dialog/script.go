@@ -0,0 +1,546 @@
+package dialog
+
+import(
+	"fmt"
+	"strings"
+)
+
+// Dialog scripts describe dialog trees as text. Consecutive lines of text
+// form a page, which is shown in one dialog node, and blank lines separate
+// pages. Lines starting with '#' are comments and a leading '\' escapes
+// text which would otherwise be read as a comment or directive. Indentation
+// is ignored. Directives start with '@':
+//
+//	@label NAME          names the node that follows
+//	@goto NAME           continues at a label
+//	@stop                ends the dialog
+//	@set FLAG VALUE      assigns a flag
+//	@effect NAME ARGS    performs an effect
+//	@if FLAG OP VALUE    branches on a flag, OP being one of == != < >
+//	@if has FLAG         branches on whether a flag has been raised
+//	@else
+//	@end
+//	@ask QUESTION        asks a yes/no question, followed by @yes and/or @no
+//	@yes
+//	@no
+//	@end
+//	@choose QUESTION     asks a question, followed by an @option per choice
+//	@option TEXT
+//	@end
+//
+// The question of @ask and @choose may continue on the lines that follow.
+// Blocks which run out of statements continue after their @end.
+
+const ScriptExtension = ".dscript"
+
+type ScriptError struct {
+	Line int
+	Message string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+type scriptStmt interface{}
+
+type textStmt struct {
+	text string
+}
+
+type labelStmt struct {
+	line int
+	name string
+}
+
+type gotoStmt struct {
+	line int
+	name string
+}
+
+type stopStmt struct {
+}
+
+type setStmt struct {
+	name, value string
+}
+
+type effectStmt struct {
+	effect string
+}
+
+type ifStmt struct {
+	value1, operation, value2 string
+	then, otherwise []scriptStmt
+}
+
+type askStmt struct {
+	question string
+	yes, no []scriptStmt
+}
+
+type chooseStmt struct {
+	question string
+	options []string
+	bodies [][]scriptStmt
+}
+
+type scriptParser struct {
+	lines []string
+	pos int
+}
+
+// CompileScript compiles the source of a dialog script into a dialog tree
+func CompileScript(src string) (DialogTree, error) {
+	src = strings.ReplaceAll(src, "\r", "")
+	parser := &scriptParser{
+		strings.Split(src, "\n"),
+		0,
+	}
+
+	stmts, end, err := parser.block()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		parser.next()
+		return nil, parser.errorf("unexpected @%s", end)
+	}
+
+	compiler := &scriptCompiler{
+		tree: make(DialogTree, 0),
+		labels: make(map[string]int),
+		gotos: make([]scriptGoto, 0),
+	}
+	return compiler.compile(stmts)
+}
+
+func (p *scriptParser) errorf(format string, args ...interface{}) error {
+	return &ScriptError{
+		p.pos,
+		fmt.Sprintf(format, args...),
+	}
+}
+
+// next returns the next line with indentation removed, along with the
+// directive and its argument if the line holds one
+func (p *scriptParser) next() (line string, directive string, arg string, ok bool) {
+	if p.pos >= len(p.lines) {
+		return "", "", "", false
+	}
+
+	line = strings.TrimSpace(p.lines[p.pos])
+	p.pos++
+
+	if strings.HasPrefix(line, "@") {
+		fields := strings.SplitN(line[1:], " ", 2)
+		directive = fields[0]
+		if len(fields) > 1 {
+			arg = strings.TrimSpace(fields[1])
+		}
+	}
+
+	return line, directive, arg, true
+}
+
+func (p *scriptParser) unread() {
+	p.pos--
+}
+
+func unescape(line string) string {
+	if strings.HasPrefix(line, "\\") {
+		return line[1:]
+	}
+	return line
+}
+
+// text gathers lines of text until a blank line or directive is found
+func (p *scriptParser) text() []string {
+	lines := make([]string, 0, 2)
+	for {
+		line, directive, _, ok := p.next()
+		if !ok {
+			return lines
+		}
+		if directive != "" || line == "" {
+			p.unread()
+			return lines
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, unescape(line))
+	}
+}
+
+// question reads the text of @ask and @choose, which begins on the line of
+// the directive and may continue on the lines that follow
+func (p *scriptParser) question(first string) string {
+	lines := make([]string, 0, 2)
+	if first != "" {
+		lines = append(lines, first)
+	}
+	for {
+		line, _, _, ok := p.next()
+		if !ok {
+			break
+		}
+		if line != "" {
+			p.unread()
+			break
+		}
+	}
+	lines = append(lines, p.text()...)
+	return strings.Join(lines, "\n")
+}
+
+// block parses statements until a directive that closes the block is found,
+// which is returned without being consumed
+func (p *scriptParser) block() ([]scriptStmt, string, error) {
+	stmts := make([]scriptStmt, 0)
+
+	for {
+		line, directive, arg, ok := p.next()
+		if !ok {
+			return stmts, "", nil
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if directive == "" {
+			p.unread()
+			stmts = append(stmts, &textStmt{strings.Join(p.text(), "\n")})
+			continue
+		}
+
+		fields := strings.Fields(arg)
+
+		switch directive {
+			case "else", "end", "yes", "no", "option":
+				p.unread()
+				return stmts, directive, nil
+			case "label", "goto":
+				if len(fields) != 1 {
+					return nil, "", p.errorf("@%s expects a single name", directive)
+				}
+				if directive == "label" {
+					stmts = append(stmts, &labelStmt{p.pos, fields[0]})
+				} else {
+					stmts = append(stmts, &gotoStmt{p.pos, fields[0]})
+				}
+			case "stop":
+				stmts = append(stmts, &stopStmt{})
+			case "set":
+				if len(fields) < 2 {
+					return nil, "", p.errorf("@set expects a flag and a value")
+				}
+				stmts = append(stmts, &setStmt{fields[0], strings.TrimSpace(arg[len(fields[0]):])})
+			case "effect":
+				if len(fields) == 0 {
+					return nil, "", p.errorf("@effect expects the name of an effect")
+				}
+				stmts = append(stmts, &effectStmt{strings.Join(fields, " ")})
+			case "if":
+				stmt, err := p.parseIf(fields)
+				if err != nil {
+					return nil, "", err
+				}
+				stmts = append(stmts, stmt)
+			case "ask":
+				stmt, err := p.parseAsk(arg)
+				if err != nil {
+					return nil, "", err
+				}
+				stmts = append(stmts, stmt)
+			case "choose":
+				stmt, err := p.parseChoose(arg)
+				if err != nil {
+					return nil, "", err
+				}
+				stmts = append(stmts, stmt)
+			default:
+				return nil, "", p.errorf("unknown directive @%s", directive)
+		}
+	}
+}
+
+// close consumes the @end of a block, failing if the block was ended by
+// something else or never ended at all
+func (p *scriptParser) close(end string, opened int, what string) error {
+	if end == "" {
+		return &ScriptError{opened, what + " is never closed with @end"}
+	}
+	p.next()
+	if end != "end" {
+		return p.errorf("unexpected @%s inside %s", end, what)
+	}
+	return nil
+}
+
+func (p *scriptParser) parseIf(fields []string) (*ifStmt, error) {
+	opened := p.pos
+	stmt := &ifStmt{}
+
+	if len(fields) == 2 && fields[0] == OpHas {
+		stmt.value1, stmt.operation = fields[1], OpHas
+	} else if len(fields) == 3 && IsValidOperation(fields[1]) && fields[1] != OpHas {
+		stmt.value1, stmt.operation, stmt.value2 = fields[0], fields[1], fields[2]
+	} else {
+		return nil, p.errorf("@if expects FLAG OP VALUE or has FLAG")
+	}
+
+	var end string
+	var err error
+	stmt.then, end, err = p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	if end == "else" {
+		p.next()
+		stmt.otherwise, end, err = p.block()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.close(end, opened, "@if"); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *scriptParser) parseAsk(arg string) (*askStmt, error) {
+	opened := p.pos
+	stmt := &askStmt{
+		question: p.question(arg),
+	}
+
+	seen := make(map[string]bool)
+	for {
+		line, directive, _, ok := p.next()
+		if !ok {
+			return nil, &ScriptError{opened, "@ask is never closed with @end"}
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch directive {
+			case "yes", "no":
+				if seen[directive] {
+					return nil, p.errorf("@ask already has a @%s", directive)
+				}
+				seen[directive] = true
+
+				body, end, err := p.block()
+				if err != nil {
+					return nil, err
+				}
+				if end == "" {
+					return nil, &ScriptError{opened, "@ask is never closed with @end"}
+				}
+				if directive == "yes" {
+					stmt.yes = body
+				} else {
+					stmt.no = body
+				}
+			case "end":
+				return stmt, nil
+			default:
+				return nil, p.errorf("expected @yes, @no or @end after @ask")
+		}
+	}
+}
+
+func (p *scriptParser) parseChoose(arg string) (*chooseStmt, error) {
+	opened := p.pos
+	stmt := &chooseStmt{
+		question: p.question(arg),
+	}
+
+	for {
+		line, directive, option, ok := p.next()
+		if !ok {
+			return nil, &ScriptError{opened, "@choose is never closed with @end"}
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch directive {
+			case "option":
+				if option == "" {
+					return nil, p.errorf("@option expects the text of the choice")
+				}
+				body, end, err := p.block()
+				if err != nil {
+					return nil, err
+				}
+				if end == "" {
+					return nil, &ScriptError{opened, "@choose is never closed with @end"}
+				}
+				stmt.options = append(stmt.options, option)
+				stmt.bodies = append(stmt.bodies, body)
+			case "end":
+				if len(stmt.options) == 0 {
+					return nil, p.errorf("@choose has no @option")
+				}
+				return stmt, nil
+			default:
+				return nil, p.errorf("expected @option or @end after @choose")
+		}
+	}
+}
+
+type scriptGoto struct {
+	link **int
+	name string
+	line int
+}
+
+// scriptCompiler lays out nodes in the order they appear in the script.
+// Links which should point at whichever node is emitted next are kept as
+// pending until that node is known.
+type scriptCompiler struct {
+	tree DialogTree
+	entry *int
+	pending []**int
+	labels map[string]int
+	waiting []string
+	gotos []scriptGoto
+}
+
+const labelAtEnd = -1
+
+func (c *scriptCompiler) compile(stmts []scriptStmt) (DialogTree, error) {
+	c.pending = []**int{&c.entry}
+
+	err := c.block(stmts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range c.waiting {
+		c.labels[name] = labelAtEnd
+	}
+
+	for _, g := range c.gotos {
+		index, ok := c.labels[g.name]
+		if !ok {
+			return nil, &ScriptError{g.line, "unknown label " + g.name}
+		}
+		if index != labelAtEnd {
+			*g.link = Link(index)
+		}
+	}
+
+	if len(c.tree) == 0 {
+		return c.tree, nil
+	}
+
+	if c.entry == nil {
+		return nil, &ScriptError{1, "dialog ends before anything is shown"}
+	}
+
+	if *c.entry != 0 {
+		swapNodes(c.tree, 0, *c.entry)
+	}
+
+	return c.tree, nil
+}
+
+func (c *scriptCompiler) emit(node DialogNodeInterface, next ...**int) {
+	index := len(c.tree)
+	for _, link := range c.pending {
+		*link = Link(index)
+	}
+	for _, name := range c.waiting {
+		c.labels[name] = index
+	}
+	c.waiting = c.waiting[:0]
+	c.tree = append(c.tree, node)
+	c.pending = next
+}
+
+func (c *scriptCompiler) block(stmts []scriptStmt) error {
+	for _, stmt := range stmts {
+		if err := c.stmt(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branch compiles each body starting out from its own link, after which
+// whatever falls out of the bodies continues past the statement
+func (c *scriptCompiler) branch(links []**int, bodies [][]scriptStmt) error {
+	after := make([]**int, 0)
+	for i := range bodies {
+		c.pending = []**int{links[i]}
+		if err := c.block(bodies[i]); err != nil {
+			return err
+		}
+		after = append(after, c.pending...)
+	}
+	c.pending = after
+	return nil
+}
+
+func (c *scriptCompiler) stmt(stmt scriptStmt) error {
+	switch s := stmt.(type) {
+		case *textStmt:
+			node := &DialogNode{Dialog: s.text}
+			c.emit(node, &node.Next)
+		case *labelStmt:
+			if _, ok := c.labels[s.name]; ok {
+				return &ScriptError{s.line, "label " + s.name + " is already defined"}
+			}
+			for _, name := range c.waiting {
+				if name == s.name {
+					return &ScriptError{s.line, "label " + s.name + " is already defined"}
+				}
+			}
+			c.waiting = append(c.waiting, s.name)
+		case *gotoStmt:
+			for _, link := range c.pending {
+				c.gotos = append(c.gotos, scriptGoto{link, s.name, s.line})
+			}
+			c.pending = nil
+		case *stopStmt:
+			c.pending = nil
+		case *setStmt:
+			node := &DialogAssignNode{Set: s.name, To: s.value}
+			c.emit(node, &node.Next)
+		case *effectStmt:
+			node := &EffectDialogNode{Effect: s.effect}
+			c.emit(node, &node.Next)
+		case *ifStmt:
+			node := &DialogBranchNode{
+				Value1: s.value1,
+				Value2: s.value2,
+				Operation: s.operation,
+			}
+			c.emit(node)
+			return c.branch([]**int{&node.True, &node.False}, [][]scriptStmt{s.then, s.otherwise})
+		case *askStmt:
+			node := &BinaryDialogNode{Dialog: s.question}
+			c.emit(node)
+			return c.branch([]**int{&node.True, &node.False}, [][]scriptStmt{s.yes, s.no})
+		case *chooseStmt:
+			node := &ChoiceDialogNode{
+				Dialog: s.question,
+				Choices: s.options,
+				Results: make([]*int, len(s.options)),
+			}
+			c.emit(node)
+			links := make([]**int, len(s.options))
+			for i := range node.Results {
+				links[i] = &node.Results[i]
+			}
+			return c.branch(links, s.bodies)
+	}
+	return nil
+}
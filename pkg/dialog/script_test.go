@@ -0,0 +1,105 @@
+package dialog
+
+import "testing"
+
+func TestCompileScript(t *testing.T) {
+	src := `# Grandma's greeting
+@if has met_grandma
+	Welcome back, dear.
+	@goto bye
+@end
+@set met_grandma true
+Oh! You must be my
+grandson's friend.
+
+@ask Would you like
+a potion?
+@yes
+	@effect give_item potion 1
+@end
+@label bye
+Bye now!
+`
+
+	tree, err := CompileScript(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validator := DialogTreeValidator{}
+	if issues := validator.Validate(&tree); len(issues) != 0 {
+		t.Errorf("Compiled tree has issues: %v", issues)
+	}
+
+	type runTest struct {
+		Flags Flags
+		Choice int
+		Want []string
+	}
+
+	tests := []runTest{
+		{Flags{}, 0, []string{"Oh! You must be my\ngrandson's friend.", "Would you like\na potion?", "give_item potion 1", "Bye now!"}},
+		{Flags{}, 1, []string{"Oh! You must be my\ngrandson's friend.", "Would you like\na potion?", "Bye now!"}},
+		{Flags{"met_grandma": "true"}, 0, []string{"Welcome back, dear.", "Bye now!"}},
+	}
+
+	for _, test := range tests {
+		coll := MakeDialogTreeCollector(&tree, test.Flags)
+		got := make([]string, 0)
+		for res := coll.Peek(); res != nil; res = coll.Peek() {
+			switch res.NodeId {
+				case BinaryDialogNodeId:
+					got = append(got, res.Dialog)
+					coll.Choose(test.Choice)
+				case EffectDialogNodeId:
+					got = append(got, res.Opt)
+					coll.CollectOnce()
+				default:
+					got = append(got, res.Dialog)
+					coll.CollectOnce()
+			}
+		}
+
+		if len(got) != len(test.Want) {
+			t.Errorf("Expected %q, got %q", test.Want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Want[i] {
+				t.Errorf("Expected %q, got %q", test.Want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestCompileScriptErrors(t *testing.T) {
+	type errorTest struct {
+		Src string
+		Line int
+	}
+
+	tests := []errorTest{
+		{"Hello\n@goto nowhere", 2},
+		{"@label a\nHi\n@label a\nHo", 3},
+		{"@if has x\nHi", 1},
+		{"@if x ~ y\nHi\n@end", 1},
+		{"Hi\n@end", 2},
+		{"@ask Well?\n@yes\nOk\n@option Huh\n@end", 4},
+		{"@choose Well?\n@end", 2},
+		{"@frobnicate", 1},
+		{"@stop\nHi", 1},
+	}
+
+	for _, test := range tests {
+		_, err := CompileScript(test.Src)
+		scriptErr, ok := err.(*ScriptError)
+		if !ok {
+			t.Errorf("Compiling %q should fail with a script error, got %v", test.Src, err)
+			continue
+		}
+		if scriptErr.Line != test.Line {
+			t.Errorf("Compiling %q should fail on line %d, got %v", test.Src, test.Line, scriptErr)
+		}
+	}
+}
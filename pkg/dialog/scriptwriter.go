@@ -0,0 +1,357 @@
+package dialog
+
+import(
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ScriptWriter decompiles a dialog tree back into a dialog script. Nodes
+// reached from more than one place are given labels named after their index,
+// everything else is written out where it is used.
+type ScriptWriter struct {
+	tree *DialogTree
+	builder strings.Builder
+	depth int
+	heads []bool
+	written []bool
+	indegree []int
+	queue []int
+	err error
+}
+
+func (w *ScriptWriter) Write(tree *DialogTree) (string, error) {
+	w.tree = tree
+	w.builder.Reset()
+	w.depth = 0
+	w.heads = make([]bool, len(*tree))
+	w.written = make([]bool, len(*tree))
+	w.indegree = make([]int, len(*tree))
+	w.queue = make([]int, 0)
+	w.err = nil
+
+	if len(*tree) == 0 {
+		return "", nil
+	}
+
+	w.indegree[0]++
+	for _, node := range *tree {
+		for _, link := range nodeLinks(node) {
+			if *link == nil {
+				continue
+			}
+			if **link < 0 || **link >= len(*tree) {
+				return "", fmt.Errorf("link to node %d is out of range", **link)
+			}
+			w.indegree[**link]++
+		}
+	}
+
+	w.section(0)
+	for len(w.queue) > 0 {
+		head := w.queue[0]
+		w.queue = w.queue[1:]
+		w.section(head)
+	}
+
+	// Keep nodes nobody links to, so that nothing is lost along the way
+	for pass := 0; pass < 2; pass++ {
+		for i := range *tree {
+			if !w.written[i] && (pass == 1 || w.indegree[i] == 0) {
+				w.heads[i] = true
+				w.section(i)
+				for len(w.queue) > 0 {
+					head := w.queue[0]
+					w.queue = w.queue[1:]
+					w.section(head)
+				}
+			}
+		}
+	}
+
+	return w.builder.String(), w.err
+}
+
+func label(index int) string {
+	return "n" + strconv.Itoa(index)
+}
+
+func (w *ScriptWriter) labelled(index int) bool {
+	return w.indegree[index] > 1 || w.heads[index]
+}
+
+func (w *ScriptWriter) section(index int) {
+	if w.written[index] {
+		return
+	}
+
+	if w.builder.Len() > 0 {
+		w.builder.WriteString("\n")
+	}
+	if w.labelled(index) {
+		w.line("@label " + label(index))
+	}
+	w.visit(index)
+}
+
+func (w *ScriptWriter) visit(index int) {
+	w.written[index] = true
+	(*w.tree)[index].Visit(w)
+}
+
+func (w *ScriptWriter) line(str string) {
+	w.builder.WriteString(strings.Repeat("\t", w.depth))
+	w.builder.WriteString(str)
+	w.builder.WriteString("\n")
+}
+
+func (w *ScriptWriter) fail(format string, args ...interface{}) {
+	if w.err == nil {
+		w.err = fmt.Errorf(format, args...)
+	}
+}
+
+// follow continues the script with the node next links to
+func (w *ScriptWriter) follow(next *int) {
+	if next == nil {
+		w.line("@stop")
+		return
+	}
+
+	if w.labelled(*next) || w.written[*next] {
+		if !w.written[*next] {
+			w.heads[*next] = true
+			w.queue = append(w.queue, *next)
+		}
+		w.line("@goto " + label(*next))
+		return
+	}
+
+	w.visit(*next)
+}
+
+// block writes a branch of a node, which always ends by leaving the script
+// so that nothing falls through to what comes after the block
+func (w *ScriptWriter) block(next *int) {
+	w.depth++
+	w.follow(next)
+	w.depth--
+}
+
+func escape(line string) string {
+	if line == "" || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "\\") {
+		return "\\" + line
+	}
+	return line
+}
+
+// textLines splits dialog into lines, dropping carriage returns and
+// whitespace which scripts cannot hold anyway
+func textLines(str string) []string {
+	str = normalizeText(str)
+	if str == "" {
+		return []string{}
+	}
+	return strings.Split(str, "\n")
+}
+
+func (w *ScriptWriter) text(str string) {
+	for _, line := range textLines(str) {
+		w.line(escape(line))
+	}
+}
+
+func isWord(str string) bool {
+	return str != "" && len(strings.Fields(str)) == 1 && strings.TrimSpace(str) == str
+}
+
+func (w *ScriptWriter) VisitDialog(d *DialogNode) {
+	lines := textLines(d.Dialog)
+	if len(lines) == 0 {
+		w.fail("dialog node without any text cannot be written as a script")
+	}
+	w.text(d.Dialog)
+	w.builder.WriteString("\n")
+	w.follow(d.Next)
+}
+
+func (w *ScriptWriter) VisitBinary(b *BinaryDialogNode) {
+	w.line("@ask")
+	w.text(b.Dialog)
+	w.line("@yes")
+	w.block(b.True)
+	w.line("@no")
+	w.block(b.False)
+	w.line("@end")
+}
+
+func (w *ScriptWriter) VisitChoice(c *ChoiceDialogNode) {
+	if len(c.Choices) != len(c.Results) || len(c.Choices) == 0 {
+		w.fail("choice node with %d choices and %d results cannot be written as a script", len(c.Choices), len(c.Results))
+		return
+	}
+
+	w.line("@choose")
+	w.text(c.Dialog)
+	for i := range c.Choices {
+		option := strings.TrimSpace(c.Choices[i])
+		if option == "" || strings.Contains(option, "\n") {
+			w.fail("choice %q cannot be written as a script", c.Choices[i])
+		}
+		w.line("@option " + option)
+		w.block(c.Results[i])
+	}
+	w.line("@end")
+}
+
+func (w *ScriptWriter) VisitEffect(e *EffectDialogNode) {
+	name, args := ParseEffect(e.Effect)
+	if name == "" {
+		w.fail("effect node without an effect cannot be written as a script")
+	}
+	w.line("@effect " + strings.Join(append([]string{name}, args...), " "))
+	w.follow(e.Next)
+}
+
+func (w *ScriptWriter) VisitBranch(b *DialogBranchNode) {
+	if !isWord(b.Value1) {
+		w.fail("branch on flag %q cannot be written as a script", b.Value1)
+	}
+
+	if b.Operation == OpHas {
+		w.line("@if has " + b.Value1)
+	} else {
+		if !isWord(b.Value2) || !IsValidOperation(b.Operation) {
+			w.fail("branch %q %q %q cannot be written as a script", b.Value1, b.Operation, b.Value2)
+		}
+		w.line("@if " + b.Value1 + " " + b.Operation + " " + b.Value2)
+	}
+
+	w.block(b.True)
+	w.line("@else")
+	w.block(b.False)
+	w.line("@end")
+}
+
+func (w *ScriptWriter) VisitAssign(a *DialogAssignNode) {
+	if !isWord(a.Set) || strings.TrimSpace(a.To) == "" {
+		w.fail("assignment of %q to %q cannot be written as a script", a.To, a.Set)
+	}
+	w.line("@set " + a.Set + " " + strings.TrimSpace(a.To))
+	w.follow(a.Next)
+}
+
+// normalizeText strips carriage returns along with whitespace surrounding
+// each line and trailing empty lines
+func normalizeText(str string) string {
+	lines := strings.Split(strings.ReplaceAll(str, "\r", ""), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	for len(lines) > 0 && lines[len(lines) - 1] == "" {
+		lines = lines[:len(lines) - 1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Equivalent reports whether two trees lead the player through the same
+// dialog, regardless of how their nodes are ordered and of whitespace
+func Equivalent(a, b *DialogTree) bool {
+	if len(*a) == 0 || len(*b) == 0 {
+		return len(*a) == len(*b)
+	}
+
+	mapping := make(map[int]int)
+	reverse := make(map[int]int)
+
+	var same func(i, j int) bool
+	same = func(i, j int) bool {
+		if i < 0 || i >= len(*a) || j < 0 || j >= len(*b) {
+			return false
+		}
+		if mapped, ok := mapping[i]; ok {
+			return mapped == j
+		}
+		if _, ok := reverse[j]; ok {
+			return false
+		}
+		mapping[i] = j
+		reverse[j] = i
+
+		x, y := (*a)[i], (*b)[j]
+		if x.GetNodeId() != y.GetNodeId() || !sameContent(x, y) {
+			return false
+		}
+
+		xs, ys := nodeLinks(x), nodeLinks(y)
+		if len(xs) != len(ys) {
+			return false
+		}
+		for k := range xs {
+			if (*xs[k] == nil) != (*ys[k] == nil) {
+				return false
+			}
+			if *xs[k] != nil && !same(**xs[k], **ys[k]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return same(0, 0)
+}
+
+func sameContent(x, y DialogNodeInterface) bool {
+	switch x := x.(type) {
+		case *DialogNode:
+			return normalizeText(x.Dialog) == normalizeText(y.(*DialogNode).Dialog)
+		case *BinaryDialogNode:
+			return normalizeText(x.Dialog) == normalizeText(y.(*BinaryDialogNode).Dialog)
+		case *ChoiceDialogNode:
+			other := y.(*ChoiceDialogNode)
+			if normalizeText(x.Dialog) != normalizeText(other.Dialog) || len(x.Choices) != len(other.Choices) {
+				return false
+			}
+			for i := range x.Choices {
+				if strings.TrimSpace(x.Choices[i]) != strings.TrimSpace(other.Choices[i]) {
+					return false
+				}
+			}
+			return true
+		case *EffectDialogNode:
+			n1, a1 := ParseEffect(x.Effect)
+			n2, a2 := ParseEffect(y.(*EffectDialogNode).Effect)
+			return n1 == n2 && strings.Join(a1, " ") == strings.Join(a2, " ")
+		case *DialogBranchNode:
+			other := y.(*DialogBranchNode)
+			return x.Value1 == other.Value1 && x.Operation == other.Operation && (x.Operation == OpHas || x.Value2 == other.Value2)
+		case *DialogAssignNode:
+			other := y.(*DialogAssignNode)
+			return x.Set == other.Set && strings.TrimSpace(x.To) == strings.TrimSpace(other.To)
+	}
+	return false
+}
+
+var ErrNotEquivalent = errors.New("decompiled script does not compile back into an equivalent tree")
+
+// Decompile writes tree as a script and makes sure the script compiles back
+// into an equivalent tree
+func Decompile(tree *DialogTree) (string, error) {
+	writer := ScriptWriter{}
+	src, err := writer.Write(tree)
+	if err != nil {
+		return "", err
+	}
+
+	other, err := CompileScript(src)
+	if err != nil {
+		return "", err
+	}
+
+	if !Equivalent(tree, &other) {
+		return src, ErrNotEquivalent
+	}
+
+	return src, nil
+}
@@ -0,0 +1,94 @@
+package dialog
+
+import(
+	"strings"
+	"testing"
+)
+
+func TestDecompile(t *testing.T) {
+	trees := []DialogTree{
+		// Shared node reached from both answers
+		{
+			&BinaryDialogNode{Dialog: "Heal your\nparty?", True: Link(1), False: Link(2)},
+			&EffectDialogNode{Effect: "set_flag healed", Next: Link(2)},
+			&DialogNode{Dialog: "We hope to see\r\nyou again!", Next: nil},
+		},
+		// Loop back to the first node, with lines that need escaping
+		{
+			&DialogNode{Dialog: "@home\n#1 trainer", Next: Link(1)},
+			&ChoiceDialogNode{Dialog: "Again?", Choices: []string{"YES", "NO", "MAYBE"}, Results: []*int{Link(0), nil, Link(2)}},
+			&DialogBranchNode{Value1: "count", Operation: OpLess, Value2: "3", True: Link(3), False: Link(1)},
+			&DialogAssignNode{Set: "count", To: "3", Next: Link(0)},
+		},
+		// Node nothing links to
+		{
+			&DialogNode{Dialog: "Hi", Next: nil},
+			&DialogNode{Dialog: "Lost\n\\", Next: Link(0)},
+		},
+	}
+
+	for i := range trees {
+		src, err := Decompile(&trees[i])
+		if err != nil {
+			t.Errorf("Tree %d: %v\n%s", i, err, src)
+			continue
+		}
+		other, _ := CompileScript(src)
+		if len(other) != len(trees[i]) {
+			t.Errorf("Tree %d: got %d nodes, want %d\n%s", i, len(other), len(trees[i]), src)
+		}
+	}
+}
+
+func TestDecompileLabels(t *testing.T) {
+	tree := DialogTree{
+		&BinaryDialogNode{Dialog: "Ready?", True: Link(1), False: Link(1)},
+		&DialogNode{Dialog: "Okay.", Next: nil},
+	}
+
+	writer := ScriptWriter{}
+	src, err := writer.Write(&tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(src, "@label n1") || strings.Count(src, "@goto n1") != 2 {
+		t.Errorf("Shared node was not labelled:\n%s", src)
+	}
+}
+
+func TestDecompileUnrepresentable(t *testing.T) {
+	trees := []DialogTree{
+		{&DialogNode{Dialog: "", Next: nil}},
+		{&DialogAssignNode{Set: "two words", To: "x", Next: nil}},
+		{&DialogBranchNode{Value1: "a", Operation: OpEqual, Value2: "", True: nil, False: nil}},
+		{&ChoiceDialogNode{Dialog: "?", Choices: []string{"A"}, Results: []*int{}}},
+	}
+
+	for i := range trees {
+		if _, err := Decompile(&trees[i]); err == nil {
+			t.Errorf("Tree %d: expected an error", i)
+		}
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	a := DialogTree{
+		&DialogNode{Dialog: "One", Next: Link(1)},
+		&DialogNode{Dialog: "Two", Next: nil},
+	}
+	b := DialogTree{
+		&DialogNode{Dialog: "Two\r\n", Next: nil},
+		&DialogNode{Dialog: "One", Next: Link(0)},
+	}
+	swapNodes(b, 0, 1)
+	if !Equivalent(&a, &b) {
+		t.Errorf("Reordered trees should be equivalent")
+	}
+
+	c := DialogTree{
+		&DialogNode{Dialog: "One", Next: Link(0)},
+	}
+	if Equivalent(&a, &c) {
+		t.Errorf("Different trees should not be equivalent")
+	}
+}
@@ -0,0 +1,163 @@
+package dialog
+
+import(
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Upper bound on how many nodes a simulation passes through before it is
+// considered stuck
+const maxSimulationSteps = 10000
+
+// Transcript records what a player would see while going through a tree
+type Transcript struct {
+	Lines []string
+	Effects []string
+	Flags Flags
+	UnusedChoices int
+	Err error
+}
+
+// Simulator runs dialog trees without a game. Text, if not nil, is applied to
+// every text before it is recorded.
+type Simulator struct {
+	Text func(string) string
+}
+
+func (s *Simulator) text(str string) string {
+	if s.Text == nil {
+		return str
+	}
+	return s.Text(str)
+}
+
+// Run takes tree through a collector, answering each question with the next
+// of choices, starting out from a copy of flags. Effects are recorded, and
+// those which only change flags are performed.
+func (s *Simulator) Run(tree *DialogTree, flags Flags, choices []int) *Transcript {
+	t := &Transcript{
+		Lines: make([]string, 0),
+		Effects: make([]string, 0),
+		Flags: make(Flags),
+	}
+	for key, value := range flags {
+		t.Flags[key] = value
+	}
+
+	coll := MakeDialogTreeCollector(tree, t.Flags)
+	steps := 0
+	for res := coll.Peek(); res != nil; res = coll.Peek() {
+		steps++
+		if steps > maxSimulationSteps {
+			t.Err = fmt.Errorf("dialog did not end within %d steps", maxSimulationSteps)
+			return t
+		}
+
+		switch res.NodeId {
+			case DialogNodeId:
+				t.add("text", s.text(res.Dialog))
+				coll.CollectOnce()
+			case EffectDialogNodeId:
+				t.Lines = append(t.Lines, "effect: " + res.Opt)
+				t.Effects = append(t.Effects, res.Opt)
+				if err := performFlagEffect(res.Opt, t.Flags); err != nil {
+					t.Err = fmt.Errorf("effect \"%s\": %s", res.Opt, err.Error())
+					return t
+				}
+				coll.CollectOnce()
+			case BinaryDialogNodeId, ChoiceDialogNodeId:
+				options := make([]string, len(res.Choices))
+				for i := range res.Choices {
+					options[i] = s.text(res.Choices[i])
+				}
+				if res.NodeId == BinaryDialogNodeId {
+					t.add("ask", s.text(res.Dialog))
+					options = []string{s.text(YesNoChoices[0]), s.text(YesNoChoices[1])}
+				} else {
+					t.add("choose", s.text(res.Dialog))
+				}
+
+				if len(choices) == 0 {
+					t.Err = fmt.Errorf("ran out of choices, options were %s", strings.Join(options, ", "))
+					return t
+				}
+
+				choice := choices[0]
+				choices = choices[1:]
+				if choice < 0 || choice >= len(options) {
+					t.Lines = append(t.Lines, fmt.Sprintf("  > %d (out of range, ends the dialog)", choice))
+				} else {
+					t.Lines = append(t.Lines, fmt.Sprintf("  > %d %s", choice, options[choice]))
+				}
+				coll.Choose(choice)
+		}
+	}
+
+	t.Lines = append(t.Lines, "end")
+	t.UnusedChoices = len(choices)
+	return t
+}
+
+// performFlagEffect performs effect on flags if it is one of FlagEffects
+func performFlagEffect(effect string, flags Flags) error {
+	name, args := ParseEffect(effect)
+	fe, ok := FlagEffects[name]
+	if !ok {
+		return nil
+	}
+	if len(args) != fe.NArgs {
+		return fmt.Errorf("expects %d arguments, got %d", fe.NArgs, len(args))
+	}
+	return fe.Do(flags, args)
+}
+
+// add records text shown to the player, indenting lines after the first
+func (t *Transcript) add(kind string, text string) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r", ""), "\n")
+	t.Lines = append(t.Lines, kind + ": " + lines[0])
+	for _, line := range lines[1:] {
+		t.Lines = append(t.Lines, strings.Repeat(" ", len(kind) + 2) + line)
+	}
+}
+
+func (t *Transcript) String() string {
+	builder := strings.Builder{}
+	for _, line := range t.Lines {
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+
+	if t.Err != nil {
+		fmt.Fprintf(&builder, "error: %s\n", t.Err.Error())
+	}
+	if t.UnusedChoices > 0 {
+		fmt.Fprintf(&builder, "unused choices: %d\n", t.UnusedChoices)
+	}
+
+	builder.WriteString("effects:")
+	if len(t.Effects) == 0 {
+		builder.WriteString(" none")
+	}
+	builder.WriteString("\n")
+	for _, effect := range t.Effects {
+		fmt.Fprintf(&builder, "  %s\n", effect)
+	}
+
+	keys := make([]string, 0, len(t.Flags))
+	for key := range t.Flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	builder.WriteString("flags:")
+	if len(keys) == 0 {
+		builder.WriteString(" none")
+	}
+	builder.WriteString("\n")
+	for _, key := range keys {
+		fmt.Fprintf(&builder, "  %s = %s\n", key, t.Flags[key])
+	}
+
+	return builder.String()
+}
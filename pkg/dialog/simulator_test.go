@@ -0,0 +1,98 @@
+package dialog
+
+import(
+	"strings"
+	"testing"
+)
+
+func TestSimulator(t *testing.T) {
+	tree := DialogTree{
+		&DialogBranchNode{"met", "", OpHas, Link(5), Link(1)},
+		&DialogAssignNode{"met", "true", Link(2)},
+		&ChoiceDialogNode{"Want one?\nPick!", []string{"BALL", "POTION", "NONE"}, []*int{Link(3), Link(4), nil}},
+		&EffectDialogNode{"give_item ball 1", Link(5)},
+		&EffectDialogNode{"give_item potion 1", Link(5)},
+		&DialogNode{"Bye!", nil},
+	}
+
+	type simulatorTest struct {
+		Flags Flags
+		Choices []int
+		Want string
+	}
+
+	tests := []simulatorTest{
+		{Flags{}, []int{1}, `choose: Want one?
+        Pick!
+  > 1 POTION
+effect: give_item potion 1
+text: Bye!
+end
+effects:
+  give_item potion 1
+flags:
+  item:potion = 1
+  met = true
+`},
+		{Flags{"met": "true"}, []int{0}, `text: Bye!
+end
+unused choices: 1
+effects: none
+flags:
+  met = true
+`},
+		{Flags{}, []int{}, `choose: Want one?
+        Pick!
+error: ran out of choices, options were BALL, POTION, NONE
+effects: none
+flags:
+  met = true
+`},
+	}
+
+	for i, test := range tests {
+		simulator := Simulator{}
+		got := simulator.Run(&tree, test.Flags, test.Choices).String()
+		if got != test.Want {
+			t.Errorf("Simulation %d: expected\n%s\ngot\n%s", i, test.Want, got)
+		}
+	}
+
+	if len(tests[0].Flags) != 0 {
+		t.Errorf("Simulation changed the flags it was given")
+	}
+
+	simulator := Simulator{Text: strings.ToUpper}
+	got := simulator.Run(&tree, Flags{"met": "true"}, nil).String()
+	if !strings.HasPrefix(got, "text: BYE!") {
+		t.Errorf("Text was not transformed:\n%s", got)
+	}
+}
+
+func TestSimulatorBinary(t *testing.T) {
+	tree := DialogTree{
+		&BinaryDialogNode{"Surf?", Link(1), nil},
+		&EffectDialogNode{"set_flag surfed", nil},
+	}
+	texts := map[string]string{"$ui.yes": "JA", "$ui.no": "NEJ"}
+	simulator := Simulator{Text: func(str string) string {
+		if text, ok := texts[str]; ok {
+			return text
+		}
+		return str
+	}}
+
+	got := simulator.Run(&tree, Flags{}, []int{0}).String()
+	want := `ask: Surf?
+  > 0 JA
+effect: set_flag surfed
+end
+effects:
+  set_flag surfed
+flags:
+  surfed = true
+`
+	if got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
@@ -0,0 +1,39 @@
+package dialog
+
+// textCollector gathers pointers to every text of a node shown to the player,
+// so that texts can be rewritten without caring about the type of node
+type textCollector struct {
+	texts []*string
+}
+
+// NodeTexts returns the texts shown by node, the question of a choice coming
+// before each of its choices
+func NodeTexts(node DialogNodeInterface) []*string {
+	collector := &textCollector{}
+	node.Visit(collector)
+	return collector.texts
+}
+
+func (t *textCollector) VisitDialog(d *DialogNode) {
+	t.texts = append(t.texts, &d.Dialog)
+}
+
+func (t *textCollector) VisitBinary(b *BinaryDialogNode) {
+	t.texts = append(t.texts, &b.Dialog)
+}
+
+func (t *textCollector) VisitChoice(c *ChoiceDialogNode) {
+	t.texts = append(t.texts, &c.Dialog)
+	for i := range c.Choices {
+		t.texts = append(t.texts, &c.Choices[i])
+	}
+}
+
+func (t *textCollector) VisitEffect(e *EffectDialogNode) {
+}
+
+func (t *textCollector) VisitBranch(b *DialogBranchNode) {
+}
+
+func (t *textCollector) VisitAssign(a *DialogAssignNode) {
+}
@@ -0,0 +1,99 @@
+package dialog
+
+import(
+	"golang.org/x/image/font"
+	"strings"
+)
+
+// Line is the range of letters [Start, End) of a text shown on one line. The
+// space or newline a line was broken at belongs to neither line.
+type Line struct {
+	Start int
+	End int
+}
+
+// measure returns the width of text in pixels, or in letters if face is nil
+func measure(face font.Face, text []rune) int {
+	if face == nil {
+		return len(text)
+	}
+	return font.MeasureString(face, string(text)).Ceil()
+}
+
+// Wrap breaks text into lines no wider than width as measured with face.
+// Lines are broken at newlines and at the last space that fits, words too
+// wide for a line of their own are broken wherever they have to be. A break
+// at the very end of text does not start another line. If face is nil, width
+// is counted in letters.
+func Wrap(face font.Face, text []rune, width int) []Line {
+	lines := make([]Line, 0, 2)
+	start := 0
+	lastSpace := -1
+
+	for i := 0; i < len(text); {
+		if text[i] == '\n' {
+			lines = append(lines, Line{start, i})
+			start = i + 1
+			lastSpace = -1
+			i++
+			continue
+		}
+
+		if measure(face, text[start:i + 1]) > width {
+			if text[i] == ' ' {
+				lines = append(lines, Line{start, i})
+				start = i + 1
+				lastSpace = -1
+				i++
+				continue
+			}
+			if lastSpace > start {
+				lines = append(lines, Line{start, lastSpace})
+				start = lastSpace + 1
+				lastSpace = -1
+				i = start
+				continue
+			}
+			if i > start {
+				lines = append(lines, Line{start, i})
+				start = i
+				lastSpace = -1
+				continue
+			}
+		}
+
+		if text[i] == ' ' {
+			lastSpace = i
+		}
+		i++
+	}
+
+	// Text ending in a break would otherwise end in an empty line, which
+	// could make up a blank page of its own
+	if start == len(text) && len(lines) > 0 {
+		return lines
+	}
+	return append(lines, Line{start, len(text)})
+}
+
+// Paginate splits lines into pages of at most perPage lines each
+func Paginate(lines []Line, perPage int) [][]Line {
+	pages := make([][]Line, 0, len(lines) / perPage + 1)
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	return append(pages, lines)
+}
+
+// LongWords returns the words of str which are too wide to fit on a line and
+// would have to be broken apart. If face is nil, width is counted in letters.
+func LongWords(face font.Face, str string, width int) []string {
+	words := make([]string, 0)
+	for _, word := range strings.Fields(str) {
+		if measure(face, []rune(word)) > width {
+			words = append(words, word)
+		}
+	}
+	return words
+}
@@ -0,0 +1,59 @@
+package dialog
+
+import(
+	"golang.org/x/image/font/basicfont"
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	type wrapTest struct {
+		Input string
+		Width int
+		Want []string
+	}
+
+	tests := []wrapTest{
+		{"", 10, []string{""}},
+		{"Short", 10, []string{"Short"}},
+		{"One two three four", 10, []string{"One two", "three four"}},
+		{"Hard\nbreak here", 10, []string{"Hard", "break here"}},
+		{"Overwhelmingly", 5, []string{"Overw", "helmi", "ngly"}},
+		{"Ends with space ", 15, []string{"Ends with space"}},
+		{"Ends with newline\n", 20, []string{"Ends with newline"}},
+		{"Two\n\n", 10, []string{"Two", ""}},
+		{"\n", 10, []string{""}},
+	}
+
+	for _, test := range tests {
+		text := []rune(test.Input)
+		got := make([]string, 0)
+		// basicfont letters are all 7 pixels wide
+		for _, line := range Wrap(basicfont.Face7x13, text, test.Width * 7) {
+			got = append(got, string(text[line.Start:line.End]))
+		}
+		if !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("%q: expected %q, got %q", test.Input, test.Want, got)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	text := []rune("One two\nthree four\n")
+	if pages := Paginate(Wrap(nil, text, 10), 2); len(pages) != 1 {
+		t.Errorf("Expected a trailing newline not to add a page, got %v", pages)
+	}
+
+	lines := []Line{{0, 1}, {2, 3}, {4, 5}, {6, 7}, {8, 9}}
+	pages := Paginate(lines, 2)
+	if len(pages) != 3 || len(pages[0]) != 2 || len(pages[2]) != 1 {
+		t.Errorf("Expected pages of 2, 2 and 1 lines, got %v", pages)
+	}
+}
+
+func TestLongWords(t *testing.T) {
+	got := LongWords(nil, "A perfectly reasonable sentence", 9)
+	if !reflect.DeepEqual(got, []string{"reasonable"}) {
+		t.Errorf("Expected only \"reasonable\" to be too long, got %q", got)
+	}
+}
@@ -23,5 +23,5 @@ func LoadFont(path string, size float64) (font.Face, error) {
 		DPI:     dpi,
 		Hinting: font.HintingFull,
 	})
-	return font, nil
+	return font, err
 }
@@ -0,0 +1,173 @@
+package locale
+
+import(
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Text referencing a string table entry is written as "$key", while "$$"
+// stands for a literal "$" at the start of a text.
+
+const DefaultLocale = "en"
+
+const Extension = ".json"
+
+// Table maps keys to the text of a single locale
+type Table map[string]string
+
+var current = Table{}
+var fallback = Table{}
+
+func Path(dir, locale string) string {
+	return filepath.Join(dir, locale + Extension)
+}
+
+func LoadTable(path string) (Table, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	table := Table{}
+	err = json.Unmarshal(bytes, &table)
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t Table) Save(path string) error {
+	bytes, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(bytes, '\n'), 0644)
+}
+
+// Merge adds every key of base missing from the table with an empty text,
+// returning how many keys were added
+func (t Table) Merge(base Table) int {
+	added := 0
+	for key := range base {
+		if _, ok := t[key]; !ok {
+			t[key] = ""
+			added++
+		}
+	}
+	return added
+}
+
+// Missing returns, in order, the keys which lack a text in the table
+func (t Table) Missing(keys []string) []string {
+	missing := make([]string, 0)
+	for _, key := range keys {
+		if t[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (t Table) Keys() []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Locales lists the locales with a string table in dir
+func Locales(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	locales := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), Extension) {
+			locales = append(locales, strings.TrimSuffix(info.Name(), Extension))
+		}
+	}
+	return locales, nil
+}
+
+// Use loads the string table of locale from dir, falling back on the table
+// of the default locale for keys it lacks
+func Use(dir, locale string) error {
+	var err error
+	fallback, err = LoadTable(Path(dir, DefaultLocale))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if fallback == nil {
+		fallback = Table{}
+	}
+
+	if locale == DefaultLocale {
+		current = fallback
+		return nil
+	}
+
+	current, err = LoadTable(Path(dir, locale))
+	return err
+}
+
+func IsKeyChar(r rune) bool {
+	return r == '_' || r == '.' || r == '-' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// Key returns the key str references, if it references any
+func Key(str string) (string, bool) {
+	if !strings.HasPrefix(str, "$") || strings.HasPrefix(str, "$$") || len(str) < 2 {
+		return "", false
+	}
+
+	key := str[1:]
+	for _, r := range key {
+		if !IsKeyChar(r) {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// Ref returns the text referencing key
+func Ref(key string) string {
+	return "$" + key
+}
+
+// Escape makes sure str is shown as it is written and not looked up
+func Escape(str string) string {
+	if strings.HasPrefix(str, "$") {
+		return "$" + str
+	}
+	return str
+}
+
+// Text returns what str should be shown as in the current locale. Keys that
+// cannot be found anywhere are shown as they are written.
+func Text(str string) string {
+	key, ok := Key(str)
+	if !ok {
+		if strings.HasPrefix(str, "$$") {
+			return str[1:]
+		}
+		return str
+	}
+
+	if text := current[key]; text != "" {
+		return text
+	}
+	if text := fallback[key]; text != "" {
+		return text
+	}
+	return str
+}
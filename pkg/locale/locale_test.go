@@ -0,0 +1,51 @@
+package locale
+
+import(
+	"reflect"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	current = Table{"greet": "Hej!", "empty": ""}
+	fallback = Table{"greet": "Hi!", "empty": "Nothing", "bye": "Bye!"}
+
+	type textTest struct {
+		Input string
+		Want string
+	}
+
+	tests := []textTest{
+		{"Plain text", "Plain text"},
+		{"$greet", "Hej!"},
+		{"$empty", "Nothing"},
+		{"$bye", "Bye!"},
+		{"$unknown", "$unknown"},
+		{"$$greet", "$greet"},
+		{"$not a key", "$not a key"},
+		{"$", "$"},
+	}
+
+	for _, test := range tests {
+		if got := Text(test.Input); got != test.Want {
+			t.Errorf("%q: expected %q, got %q", test.Input, test.Want, got)
+		}
+	}
+
+	if got := Text(Escape("$greet")); got != "$greet" {
+		t.Errorf("Escaped text was looked up as %q", got)
+	}
+}
+
+func TestMergeAndMissing(t *testing.T) {
+	base := Table{"a": "A", "b": "B", "c": "C"}
+	other := Table{"a": "Ä", "d": "D"}
+
+	if added := other.Merge(base); added != 2 {
+		t.Errorf("Expected 2 keys to be added, got %d", added)
+	}
+
+	missing := other.Missing(base.Keys())
+	if !reflect.DeepEqual(missing, []string{"b", "c"}) {
+		t.Errorf("Expected b and c to be missing, got %v", missing)
+	}
+}
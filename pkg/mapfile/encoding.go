@@ -0,0 +1,271 @@
+package mapfile
+
+import(
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Layers may either be written as nested JSON arrays, or with every layer
+// packed into a string of base64 encoded, zlib compressed little endian
+// values. Tiles and TextureIndicies use four bytes per tile, Collision one.
+const(
+	EncodingJSON = "json"
+	EncodingZlib = "zlib"
+)
+
+var Encodings = []string{
+	EncodingJSON,
+	EncodingZlib,
+}
+
+func IsValidEncoding(encoding string) bool {
+	for _, e := range Encodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Encoding returns how the layers of m are written
+func (m Map) Encoding() (string, error) {
+	encoding := EncodingJSON
+	if _, err := m.Get("Encoding", &encoding); err != nil {
+		return "", err
+	}
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	if !IsValidEncoding(encoding) {
+		return "", fmt.Errorf("Unknown map encoding %q", encoding)
+	}
+	return encoding, nil
+}
+
+// IntLayers are layers of tiles or texture indicies, which can be read from
+// either encoding
+type IntLayers [][]int
+
+// BoolLayers are layers of collision, which can be read from either encoding
+type BoolLayers [][]bool
+
+func (l *IntLayers) UnmarshalJSON(data []byte) error {
+	if !isPacked(data) {
+		return json.Unmarshal(data, (*[][]int)(l))
+	}
+	packed := make([]string, 0)
+	if err := json.Unmarshal(data, &packed); err != nil {
+		return err
+	}
+	*l = make(IntLayers, len(packed))
+	for i := range packed {
+		raw, err := inflate(packed[i])
+		if err == nil {
+			(*l)[i], err = unpackInts(raw)
+		}
+		if err != nil {
+			return fmt.Errorf("Layer %d: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+func (l *BoolLayers) UnmarshalJSON(data []byte) error {
+	if !isPacked(data) {
+		return json.Unmarshal(data, (*[][]bool)(l))
+	}
+	packed := make([]string, 0)
+	if err := json.Unmarshal(data, &packed); err != nil {
+		return err
+	}
+	*l = make(BoolLayers, len(packed))
+	for i := range packed {
+		raw, err := inflate(packed[i])
+		if err != nil {
+			return fmt.Errorf("Layer %d: %s", i, err.Error())
+		}
+		(*l)[i] = unpackBools(raw)
+	}
+	return nil
+}
+
+// isPacked reports whether the layers in data are packed into strings, by
+// looking at the first of them
+func isPacked(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 || data[0] != '[' {
+		return false
+	}
+	data = bytes.TrimLeft(data[1:], " \t\r\n")
+	return len(data) > 0 && data[0] == '"'
+}
+
+// Unpack turns the map held in data into one with its layers written as
+// JSON arrays, returning it along with the encoding it had. Data already
+// written as JSON is returned as it is.
+func Unpack(data []byte) ([]byte, string, error) {
+	m, err := Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	encoding, err := m.Encoding()
+	if err != nil || encoding == EncodingJSON {
+		return data, encoding, err
+	}
+
+	if err = m.convertLayers(EncodingJSON); err != nil {
+		return nil, encoding, err
+	}
+	delete(m, "Encoding")
+
+	data, err = json.Marshal(m)
+	return data, encoding, err
+}
+
+// Pack writes the layers of the map held in data using encoding
+func Pack(data []byte, encoding string) ([]byte, error) {
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	if !IsValidEncoding(encoding) {
+		return nil, fmt.Errorf("Unknown map encoding %q", encoding)
+	}
+
+	m, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.Encoding()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := m["Encoding"]; encoding == EncodingJSON && current == EncodingJSON && !ok {
+		return data, nil
+	}
+
+	if err = m.convertLayers(encoding); err != nil {
+		return nil, err
+	}
+	if encoding == EncodingJSON {
+		delete(m, "Encoding")
+	} else if err = m.Set("Encoding", encoding); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// convertLayers rewrites every layer of m using encoding, whichever encoding
+// they are written in now
+func (m Map) convertLayers(encoding string) error {
+	for _, field := range []string{"Tiles", "TextureIndicies"} {
+		layers := IntLayers{}
+		if ok, err := m.Get(field, &layers); !ok || err != nil {
+			if err != nil {
+				return fmt.Errorf("%s: %s", field, err.Error())
+			}
+			continue
+		}
+		if encoding == EncodingJSON {
+			if err := m.Set(field, layers); err != nil {
+				return err
+			}
+			continue
+		}
+		packed := make([]string, len(layers))
+		for i := range layers {
+			var err error
+			if packed[i], err = deflate(packInts(layers[i])); err != nil {
+				return err
+			}
+		}
+		if err := m.Set(field, packed); err != nil {
+			return err
+		}
+	}
+
+	layers := BoolLayers{}
+	if ok, err := m.Get("Collision", &layers); !ok || err != nil {
+		if err != nil {
+			return fmt.Errorf("Collision: %s", err.Error())
+		}
+		return nil
+	}
+	if encoding == EncodingJSON {
+		return m.Set("Collision", layers)
+	}
+	packed := make([]string, len(layers))
+	for i := range layers {
+		var err error
+		if packed[i], err = deflate(packBools(layers[i])); err != nil {
+			return err
+		}
+	}
+	return m.Set("Collision", packed)
+}
+
+func deflate(raw []byte) (string, error) {
+	buffer := bytes.Buffer{}
+	writer := zlib.NewWriter(&buffer)
+	if _, err := writer.Write(raw); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+func inflate(str string) ([]byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func packInts(values []int) []byte {
+	packed := make([]byte, 4 * len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(packed[4 * i:], uint32(int32(value)))
+	}
+	return packed
+}
+
+func unpackInts(raw []byte) ([]int, error) {
+	if len(raw) % 4 != 0 {
+		return nil, fmt.Errorf("%d bytes do not make up whole tiles", len(raw))
+	}
+	values := make([]int, len(raw) / 4)
+	for i := range values {
+		values[i] = int(int32(binary.LittleEndian.Uint32(raw[4 * i:])))
+	}
+	return values, nil
+}
+
+func packBools(values []bool) []byte {
+	packed := make([]byte, len(values))
+	for i, value := range values {
+		if value {
+			packed[i] = 1
+		}
+	}
+	return packed
+}
+
+func unpackBools(raw []byte) []bool {
+	values := make([]bool, len(raw))
+	for i, b := range raw {
+		values[i] = b != 0
+	}
+	return values
+}
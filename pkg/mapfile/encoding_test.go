@@ -0,0 +1,109 @@
+package mapfile
+
+import(
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackRoundTrip(t *testing.T) {
+	input := `{"FormatVersion":2,"Tiles":[[1,-1,300],[70000,0,2]],"Collision":[[true,false,true],[false,false,true]],"TextureIndicies":[[0,1,0],[2,0,0]],"Textures":["a.png","b.png","c.png"],"Width":3,"Height":1}`
+
+	want := testMap{}
+	if err := json.Unmarshal([]byte(input), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	packed, err := Pack([]byte(input), EncodingZlib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(packed), "[[") {
+		t.Errorf("Expected every layer to be packed, got %s", packed)
+	}
+
+	unpacked, encoding, err := Unpack(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoding != EncodingZlib {
+		t.Errorf("Expected encoding %q, got %q", EncodingZlib, encoding)
+	}
+
+	got := testMap{}
+	if err = json.Unmarshal(unpacked, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	plain, err := Pack(packed, EncodingJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "Encoding") {
+		t.Errorf("Expected the encoding to be left out of JSON maps, got %s", plain)
+	}
+	if _, encoding, _ = Unpack(plain); encoding != EncodingJSON {
+		t.Errorf("Expected encoding %q, got %q", EncodingJSON, encoding)
+	}
+}
+
+func TestUnpackJSON(t *testing.T) {
+	input := `{"FormatVersion":2,"Tiles":[[1]]}`
+	data, encoding, err := Unpack([]byte(input))
+	if err != nil || encoding != EncodingJSON || string(data) != input {
+		t.Errorf("JSON map should be left alone, got %s as %q: %v", data, encoding, err)
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	inputs := []string{
+		`{"Encoding":"rle","Tiles":["AAAA"]}`,
+		`{"Encoding":"zlib","Tiles":["not base64"]}`,
+		`{"Encoding":"zlib","Tiles":["AAAA"]}`,
+		`{"Encoding":"zlib","Collision":[1]}`,
+	}
+
+	for _, input := range inputs {
+		if _, _, err := Unpack([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+
+	if _, err := Pack([]byte(`{"Tiles":[[1]]}`), "rle"); err == nil {
+		t.Errorf("Expected an error when packing with an unknown encoding")
+	}
+}
+
+func TestLayers(t *testing.T) {
+	packed, err := Pack([]byte(`{"Tiles":[[1,-1],[300,0]],"Collision":[[true,false]]}`), EncodingZlib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type layers struct {
+		Tiles IntLayers
+		Collision BoolLayers
+	}
+	want := layers{
+		IntLayers{{1, -1}, {300, 0}},
+		BoolLayers{{true, false}},
+	}
+
+	inputs := []string{
+		string(packed),
+		`{"Tiles":[[1,-1],[300,0]],"Collision":[[true,false]]}`,
+		`{"Encoding":"zlib","Tiles":[ [1,-1],[300,0]],"Collision":[[true,false]]}`,
+	}
+	for _, input := range inputs {
+		got := layers{}
+		if err := json.Unmarshal([]byte(input), &got); err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", input, want, got)
+		}
+	}
+}
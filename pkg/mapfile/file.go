@@ -0,0 +1,150 @@
+package mapfile
+
+import(
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// File is a tilemap as it is written to file. The game and the tools all
+// read and write maps through it.
+type File struct {
+	FormatVersion int
+	// How the layers are written, JSON arrays if empty
+	Encoding string `json:",omitempty"`
+	Tiles IntLayers
+	Collision BoolLayers
+	TextureIndicies IntLayers
+	Textures []string
+	Exits []Exit
+	Entries []Entry
+	Width int
+	Height int
+	NpcInfo []Npc
+	Triggers []Trigger
+	Interactables []Interactable
+	Properties Properties
+}
+
+type Exit struct {
+	Target string
+	Id int
+	X int
+	Y int
+	Z int
+}
+
+type Entry struct {
+	Id int
+	X int
+	Y int
+	Z int
+}
+
+type Npc struct {
+	Texture string
+	DialogPath string
+	X, Y, Z int
+	MovementInfo Movement
+}
+
+type Movement struct {
+	Strategy int
+	Commands []int
+}
+
+type Trigger struct {
+	Id int
+	Kind int
+	X, Y, Z int
+	W, H int
+	DialogPath string
+	Effect string
+	Conditions []Condition
+	Once bool
+}
+
+type Condition struct {
+	Flag string
+	Operation string
+	Value string
+}
+
+// Conditions are written as text in Tiled and in the editor, with each
+// condition written as "flag operation value" and separated by semicolons
+const conditionSeparator = ";"
+
+func ParseConditions(str string) ([]Condition, error) {
+	conditions := make([]Condition, 0)
+	for _, field := range strings.Split(str, conditionSeparator) {
+		parts := strings.Fields(field)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Bad condition %q", strings.TrimSpace(field))
+		}
+		value := strings.Join(parts[2:], " ")
+		conditions = append(conditions, Condition{parts[0], parts[1], value})
+	}
+	return conditions, nil
+}
+
+func FormatConditions(conditions []Condition) string {
+	fields := make([]string, len(conditions))
+	for i, c := range conditions {
+		fields[i] = strings.TrimSpace(c.Flag + " " + c.Operation + " " + c.Value)
+	}
+	return strings.Join(fields, conditionSeparator + " ")
+}
+
+type Interactable struct {
+	X, Y, Z int
+	Facing int
+	DialogPath string
+	Effect string
+}
+
+type Properties struct {
+	Name string
+	Music string
+	Weather int
+	Indoor bool
+	Encounters string
+}
+
+// Decode reads the map held in data into f, returning the version it was.
+// Maps of the current version are read as they are, older maps are migrated
+// first.
+func (f *File) Decode(data []byte) (int, error) {
+	*f = File{}
+	if err := json.Unmarshal(data, f); err == nil && f.FormatVersion == FormatVersion {
+		return FormatVersion, f.validate()
+	}
+
+	data, from, err := Migrate(data)
+	if err != nil {
+		return from, err
+	}
+	*f = File{}
+	if err = json.Unmarshal(data, f); err != nil {
+		return from, err
+	}
+	return from, f.validate()
+}
+
+// Encode writes f, packing its layers as told by its encoding
+func (f *File) Encode() ([]byte, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return Pack(data, f.Encoding)
+}
+
+func (f *File) validate() error {
+	if f.Encoding != "" && !IsValidEncoding(f.Encoding) {
+		return fmt.Errorf("Unknown map encoding %q", f.Encoding)
+	}
+	return nil
+}
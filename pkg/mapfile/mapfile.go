@@ -0,0 +1,128 @@
+package mapfile
+
+import(
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FormatVersion is the version of the tilemap format written by the game and
+// the editor. Files of older versions are migrated as they are read.
+const FormatVersion = 2
+
+// Map is a tilemap file decoded only as far as its top level, so that
+// migrations can rewrite fields without knowing about the rest
+type Map map[string]json.RawMessage
+
+// Migration upgrades a map from the version it is registered for to the
+// version after it
+type Migration func(m Map) error
+
+var migrations = make(map[int]Migration)
+
+// RegisterMigration makes migration the way of upgrading maps of version from
+func RegisterMigration(from int, migration Migration) {
+	migrations[from] = migration
+}
+
+// Get decodes field into value, reporting whether the field was present
+func (m Map) Get(field string, value interface{}) (bool, error) {
+	raw, ok := m[field]
+	if !ok || string(raw) == "null" {
+		return false, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return true, decoder.Decode(value)
+}
+
+func (m Map) Set(field string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m[field] = raw
+	return nil
+}
+
+// Version returns the version of m, guessing it from the shape of the map for
+// files written before versions were introduced
+func (m Map) Version() (int, error) {
+	version := 0
+	if ok, err := m.Get("FormatVersion", &version); ok || err != nil {
+		return version, err
+	}
+
+	tiles := make([]interface{}, 0)
+	if _, err := m.Get("Tiles", &tiles); err != nil {
+		return 0, err
+	}
+	if len(tiles) > 0 {
+		if _, layered := tiles[0].([]interface{}); !layered {
+			return 0, nil
+		}
+	}
+
+	indicies := make([]interface{}, 0)
+	if ok, err := m.Get("TextureIndicies", &indicies); !ok || err != nil {
+		return 1, err
+	}
+	return 2, nil
+}
+
+// Upgrade migrates m to the current version, returning the version it was
+func (m Map) Upgrade() (int, error) {
+	from, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	if from > FormatVersion {
+		return from, fmt.Errorf("Map format version %d is newer than the supported version %d", from, FormatVersion)
+	}
+
+	for version := from; version < FormatVersion; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			return from, fmt.Errorf("No migration registered from map format version %d", version)
+		}
+		if err = migration(m); err != nil {
+			return from, fmt.Errorf("Migrating from map format version %d: %s", version, err.Error())
+		}
+	}
+
+	return from, m.Set("FormatVersion", FormatVersion)
+}
+
+func Decode(data []byte) (Map, error) {
+	m := Map{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, errors.New("Map file holds no map")
+	}
+	return m, nil
+}
+
+// Migrate upgrades the map held in data to the current version, returning
+// the upgraded map along with the version it was. Current maps lacking a
+// version are given one.
+func Migrate(data []byte) ([]byte, int, error) {
+	m, err := Decode(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, versioned := m["FormatVersion"]
+	from, err := m.Upgrade()
+	if err != nil {
+		return nil, from, err
+	}
+	if versioned && from == FormatVersion {
+		return data, from, nil
+	}
+
+	data, err = json.Marshal(m)
+	return data, from, err
+}
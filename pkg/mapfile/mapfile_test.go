@@ -0,0 +1,88 @@
+package mapfile
+
+import(
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testMap struct {
+	FormatVersion int
+	Tiles [][]int
+	Collision [][]bool
+	TextureIndicies [][]int
+	Textures []string
+	Exits []map[string]interface{}
+	Entries []map[string]interface{}
+	Width int
+	Height int
+}
+
+func TestMigrate(t *testing.T) {
+	type migrateTest struct {
+		Name string
+		Input string
+		From int
+	}
+
+	tests := []migrateTest{
+		{"flat", `{"Tiles":[1,2],"Collision":[true,false],"Exits":[{"Target":"a.json","Id":0,"X":1,"Y":0}],"Entries":[{"Id":0,"X":0,"Y":0}],"Width":2,"Height":1}`, 0},
+		{"layered", `{"Tiles":[[1,2]],"TextureIndicies":null,"Textures":null,"Collision":[[true,false]],"Exits":[{"Target":"a.json","Id":0,"X":1,"Y":0,"Z":0}],"Entries":[{"Id":0,"X":0,"Y":0,"Z":0}],"Width":2,"Height":1}`, 1},
+		{"textured", `{"Tiles":[[1,2]],"Collision":[[true,false]],"TextureIndicies":[[0,0]],"Textures":["base.png"],"Exits":[{"Target":"a.json","Id":0,"X":1,"Y":0,"Z":0}],"Entries":[{"Id":0,"X":0,"Y":0,"Z":0}],"Width":2,"Height":1}`, 2},
+	}
+
+	want := testMap{
+		FormatVersion,
+		[][]int{{1, 2}},
+		[][]bool{{true, false}},
+		[][]int{{0, 0}},
+		[]string{"base.png"},
+		[]map[string]interface{}{{"Target": "a.json", "Id": 0.0, "X": 1.0, "Y": 0.0, "Z": 0.0}},
+		[]map[string]interface{}{{"Id": 0.0, "X": 0.0, "Y": 0.0, "Z": 0.0}},
+		2,
+		1,
+	}
+
+	for _, test := range tests {
+		data, from, err := Migrate([]byte(test.Input))
+		if err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+		if from != test.From {
+			t.Errorf("%s: expected to migrate from version %d, got %d", test.Name, test.From, from)
+		}
+
+		got := testMap{}
+		if err = json.Unmarshal(data, &got); err != nil {
+			t.Errorf("%s: %v", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", test.Name, want, got)
+		}
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	input := `{"FormatVersion":2,"Tiles":[[1]]}`
+	data, from, err := Migrate([]byte(input))
+	if err != nil || from != FormatVersion || string(data) != input {
+		t.Errorf("Current map should be left alone, got %s from %d: %v", data, from, err)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	inputs := []string{
+		`{"FormatVersion":99}`,
+		`{"Tiles":[[1]],"Textures":["x.png"]}`,
+		`[1, 2, 3]`,
+		`null`,
+	}
+
+	for _, input := range inputs {
+		if _, _, err := Migrate([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
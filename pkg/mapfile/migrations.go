@@ -0,0 +1,77 @@
+package mapfile
+
+import(
+	"errors"
+)
+
+// The tileset every tile referred to before maps could use several
+const legacyTexture = "base.png"
+
+func init() {
+	RegisterMigration(0, layerMigration)
+	RegisterMigration(1, textureMigration)
+}
+
+// layerMigration moves the flat Tiles and Collision of a single layer map
+// into layers, placing exits and entries on the first layer
+func layerMigration(m Map) error {
+	tiles := make([]interface{}, 0)
+	if _, err := m.Get("Tiles", &tiles); err != nil {
+		return err
+	}
+	collision := make([]interface{}, 0)
+	if _, err := m.Get("Collision", &collision); err != nil {
+		return err
+	}
+
+	if err := m.Set("Tiles", [][]interface{}{tiles}); err != nil {
+		return err
+	}
+	if err := m.Set("Collision", [][]interface{}{collision}); err != nil {
+		return err
+	}
+
+	for _, field := range []string{"Exits", "Entries"} {
+		objects := make([]map[string]interface{}, 0)
+		if _, err := m.Get(field, &objects); err != nil {
+			return err
+		}
+		for _, object := range objects {
+			if _, ok := object["Z"]; !ok {
+				object["Z"] = 0
+			}
+		}
+		if err := m.Set(field, objects); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// textureMigration gives every tile the texture all tiles used to come from
+func textureMigration(m Map) error {
+	tiles := make([][]interface{}, 0)
+	if _, err := m.Get("Tiles", &tiles); err != nil {
+		return err
+	}
+
+	indicies := make([][]int, len(tiles))
+	for i := range tiles {
+		indicies[i] = make([]int, len(tiles[i]))
+	}
+
+	textures := make([]string, 0)
+	if _, err := m.Get("Textures", &textures); err != nil {
+		return err
+	}
+	if len(textures) > 0 {
+		return errors.New("Map has textures but no texture indicies")
+	}
+
+	if err := m.Set("TextureIndicies", indicies); err != nil {
+		return err
+	}
+	return m.Set("Textures", []string{legacyTexture})
+}
+
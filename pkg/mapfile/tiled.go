@@ -0,0 +1,622 @@
+package mapfile
+
+import(
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Formats of maps written by the Tiled editor
+const(
+	TiledJSON = "json"
+	TiledTMX = "tmx"
+)
+
+const(
+	TiledTileLayer = "tilelayer"
+	TiledObjectGroup = "objectgroup"
+	TiledImageLayer = "imagelayer"
+	TiledGroup = "group"
+)
+
+// Bits of a global tile id telling how the tile is flipped
+const tiledFlipMask = 0xF0000000
+
+// TiledMap is a map as written by Tiled in its JSON format, which is also
+// what maps in its TMX format are read into
+type TiledMap struct {
+	Type string `json:"type"`
+	Version string `json:"version"`
+	Orientation string `json:"orientation"`
+	RenderOrder string `json:"renderorder"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	TileWidth int `json:"tilewidth"`
+	TileHeight int `json:"tileheight"`
+	Infinite bool `json:"infinite"`
+	NextLayerId int `json:"nextlayerid"`
+	NextObjectId int `json:"nextobjectid"`
+	Layers []TiledLayer `json:"layers"`
+	Tilesets []TiledTileset `json:"tilesets"`
+	Properties []TiledProperty `json:"properties,omitempty"`
+}
+
+type TiledTileset struct {
+	FirstGid int `json:"firstgid"`
+	Source string `json:"source,omitempty"`
+	Name string `json:"name"`
+	TileWidth int `json:"tilewidth"`
+	TileHeight int `json:"tileheight"`
+	TileCount int `json:"tilecount"`
+	Columns int `json:"columns"`
+	Margin int `json:"margin"`
+	Spacing int `json:"spacing"`
+	Image string `json:"image"`
+	ImageWidth int `json:"imagewidth"`
+	ImageHeight int `json:"imageheight"`
+	Tiles json.RawMessage `json:"tiles,omitempty"`
+}
+
+type TiledLayer struct {
+	Id int `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Visible bool `json:"visible"`
+	Opacity float64 `json:"opacity"`
+	X int `json:"x"`
+	Y int `json:"y"`
+	OffsetX float64 `json:"offsetx,omitempty"`
+	OffsetY float64 `json:"offsety,omitempty"`
+	Width int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Either an array of global tile ids, or a string of them as described by
+	// Encoding and Compression
+	Data json.RawMessage `json:"data,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	Objects []TiledObject `json:"objects,omitempty"`
+	Layers []TiledLayer `json:"layers,omitempty"`
+	Properties []TiledProperty `json:"properties,omitempty"`
+}
+
+type TiledObject struct {
+	Id int `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Class string `json:"class,omitempty"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Width float64 `json:"width"`
+	Height float64 `json:"height"`
+	Gid uint32 `json:"gid,omitempty"`
+	Visible bool `json:"visible"`
+	Properties []TiledProperty `json:"properties,omitempty"`
+}
+
+type TiledProperty struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func IsValidTiledFormat(format string) bool {
+	return format == TiledJSON || format == TiledTMX
+}
+
+// Tiles returns the global tile ids of a tile layer
+func (l *TiledLayer) Tiles() ([]uint32, error) {
+	tiles := make([]uint32, 0)
+	if len(l.Data) == 0 || l.Data[0] == '[' {
+		if len(l.Data) > 0 {
+			if err := json.Unmarshal(l.Data, &tiles); err != nil {
+				return nil, err
+			}
+		}
+		return tiles, nil
+	}
+
+	str := ""
+	if err := json.Unmarshal(l.Data, &str); err != nil {
+		return nil, err
+	}
+	if l.Encoding != "base64" {
+		return nil, fmt.Errorf("Layer %q uses unknown encoding %q", l.Name, l.Encoding)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(str))
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = bytes.NewReader(raw)
+	switch l.Compression {
+		case "":
+		case "zlib":
+			if reader, err = zlib.NewReader(reader); err != nil {
+				return nil, err
+			}
+		case "gzip":
+			if reader, err = gzip.NewReader(reader); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Layer %q uses unsupported compression %q", l.Name, l.Compression)
+	}
+	if raw, err = ioutil.ReadAll(reader); err != nil {
+		return nil, err
+	}
+	if len(raw) % 4 != 0 {
+		return nil, fmt.Errorf("Layer %q holds %d bytes, which do not make up whole tiles", l.Name, len(raw))
+	}
+
+	tiles = make([]uint32, len(raw) / 4)
+	err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, tiles)
+	return tiles, err
+}
+
+// Property returns the value of the property called name, if there is one
+func Property(properties []TiledProperty, name string) (interface{}, bool) {
+	for _, p := range properties {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+func propertyString(properties []TiledProperty, name string) string {
+	value, _ := Property(properties, name)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func propertyInt(properties []TiledProperty, name string) (int, error) {
+	value, ok := Property(properties, name)
+	if !ok {
+		return 0, nil
+	}
+	switch v := value.(type) {
+		case float64:
+			return int(v), nil
+		case int:
+			return v, nil
+		case string:
+			return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("Property %q is not a number", name)
+}
+
+func propertyBool(properties []TiledProperty, name string) bool {
+	value, _ := Property(properties, name)
+	switch v := value.(type) {
+		case bool:
+			return v
+		case string:
+			return v == "true"
+	}
+	return false
+}
+
+// DecodeTiled reads a map written by Tiled in format
+func DecodeTiled(data []byte, format string) (*TiledMap, error) {
+	switch format {
+		case TiledJSON:
+			tm := &TiledMap{}
+			if err := json.Unmarshal(data, tm); err != nil {
+				return nil, err
+			}
+			return tm, nil
+		case TiledTMX:
+			tmx := tmxMap{}
+			if err := xml.Unmarshal(data, &tmx); err != nil {
+				return nil, err
+			}
+			return tmx.toTiled()
+	}
+	return nil, fmt.Errorf("Unknown Tiled format %q", format)
+}
+
+// EncodeTiled writes tm the way Tiled would in format
+func EncodeTiled(tm *TiledMap, format string) ([]byte, error) {
+	switch format {
+		case TiledJSON:
+			return json.MarshalIndent(tm, "", "\t")
+		case TiledTMX:
+			tmx, err := tmxFromTiled(tm)
+			if err != nil {
+				return nil, err
+			}
+			data, err := xml.MarshalIndent(tmx, "", " ")
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte(xml.Header), append(data, '\n')...), nil
+	}
+	return nil, fmt.Errorf("Unknown Tiled format %q", format)
+}
+
+// The TMX format is XML, where layers of all kinds share one list
+
+type tmxMap struct {
+	XMLName xml.Name `xml:"map"`
+	Version string `xml:"version,attr"`
+	Orientation string `xml:"orientation,attr"`
+	RenderOrder string `xml:"renderorder,attr"`
+	Width int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+	TileWidth int `xml:"tilewidth,attr"`
+	TileHeight int `xml:"tileheight,attr"`
+	Infinite int `xml:"infinite,attr"`
+	NextLayerId int `xml:"nextlayerid,attr"`
+	NextObjectId int `xml:"nextobjectid,attr"`
+	Properties *tmxProperties `xml:"properties"`
+	Tilesets []tmxTileset `xml:"tileset"`
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTileset struct {
+	FirstGid int `xml:"firstgid,attr"`
+	Source string `xml:"source,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty"`
+	TileWidth int `xml:"tilewidth,attr,omitempty"`
+	TileHeight int `xml:"tileheight,attr,omitempty"`
+	Spacing int `xml:"spacing,attr,omitempty"`
+	Margin int `xml:"margin,attr,omitempty"`
+	TileCount int `xml:"tilecount,attr,omitempty"`
+	Columns int `xml:"columns,attr,omitempty"`
+	Image *tmxImage `xml:"image"`
+	Tiles []struct{} `xml:"tile"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+}
+
+type tmxLayer struct {
+	XMLName xml.Name
+	Id int `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Width int `xml:"width,attr,omitempty"`
+	Height int `xml:"height,attr,omitempty"`
+	Visible string `xml:"visible,attr,omitempty"`
+	Opacity string `xml:"opacity,attr,omitempty"`
+	OffsetX float64 `xml:"offsetx,attr,omitempty"`
+	OffsetY float64 `xml:"offsety,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Data *tmxData `xml:"data"`
+	Objects []tmxObject `xml:"object"`
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxData struct {
+	Encoding string `xml:"encoding,attr,omitempty"`
+	Compression string `xml:"compression,attr,omitempty"`
+	Text string `xml:",chardata"`
+	Tiles []tmxTile `xml:"tile"`
+}
+
+type tmxTile struct {
+	Gid uint32 `xml:"gid,attr"`
+}
+
+type tmxObject struct {
+	Id int `xml:"id,attr"`
+	Name string `xml:"name,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Class string `xml:"class,attr,omitempty"`
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Width float64 `xml:"width,attr,omitempty"`
+	Height float64 `xml:"height,attr,omitempty"`
+	Gid uint32 `xml:"gid,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+}
+
+type tmxProperties struct {
+	Properties []tmxProperty `xml:"property"`
+}
+
+type tmxProperty struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr,omitempty"`
+	Value string `xml:"value,attr"`
+}
+
+func (p *tmxProperties) toTiled() ([]TiledProperty, error) {
+	if p == nil {
+		return nil, nil
+	}
+	properties := make([]TiledProperty, len(p.Properties))
+	for i, prop := range p.Properties {
+		properties[i] = TiledProperty{prop.Name, prop.Type, prop.Value}
+		if properties[i].Type == "" {
+			properties[i].Type = "string"
+		}
+		var err error
+		switch prop.Type {
+			case "int":
+				properties[i].Value, err = strconv.Atoi(prop.Value)
+			case "float":
+				properties[i].Value, err = strconv.ParseFloat(prop.Value, 64)
+			case "bool":
+				properties[i].Value = prop.Value == "true"
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Property %q: %s", prop.Name, err.Error())
+		}
+	}
+	return properties, nil
+}
+
+func tmxPropertiesFromTiled(properties []TiledProperty) *tmxProperties {
+	if len(properties) == 0 {
+		return nil
+	}
+	p := &tmxProperties{make([]tmxProperty, len(properties))}
+	for i, prop := range properties {
+		t := prop.Type
+		if t == "string" {
+			t = ""
+		}
+		p.Properties[i] = tmxProperty{prop.Name, t, fmt.Sprint(prop.Value)}
+	}
+	return p
+}
+
+func (tmx *tmxMap) toTiled() (*TiledMap, error) {
+	tm := &TiledMap{
+		"map",
+		tmx.Version,
+		tmx.Orientation,
+		tmx.RenderOrder,
+		tmx.Width,
+		tmx.Height,
+		tmx.TileWidth,
+		tmx.TileHeight,
+		tmx.Infinite != 0,
+		tmx.NextLayerId,
+		tmx.NextObjectId,
+		make([]TiledLayer, 0, len(tmx.Layers)),
+		make([]TiledTileset, len(tmx.Tilesets)),
+		nil,
+	}
+
+	var err error
+	if tm.Properties, err = tmx.Properties.toTiled(); err != nil {
+		return nil, err
+	}
+
+	for i, ts := range tmx.Tilesets {
+		tm.Tilesets[i] = TiledTileset{
+			FirstGid: ts.FirstGid,
+			Source: ts.Source,
+			Name: ts.Name,
+			TileWidth: ts.TileWidth,
+			TileHeight: ts.TileHeight,
+			TileCount: ts.TileCount,
+			Columns: ts.Columns,
+			Margin: ts.Margin,
+			Spacing: ts.Spacing,
+		}
+		if ts.Image != nil {
+			tm.Tilesets[i].Image = ts.Image.Source
+			tm.Tilesets[i].ImageWidth = ts.Image.Width
+			tm.Tilesets[i].ImageHeight = ts.Image.Height
+		}
+		if len(ts.Tiles) > 0 {
+			tm.Tilesets[i].Tiles, err = json.Marshal(ts.Tiles)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i := range tmx.Layers {
+		if tmx.Layers[i].XMLName.Local == "properties" {
+			continue
+		}
+		layer, err := tmx.Layers[i].toTiled()
+		if err != nil {
+			return nil, err
+		}
+		tm.Layers = append(tm.Layers, *layer)
+	}
+
+	return tm, nil
+}
+
+func (l *tmxLayer) toTiled() (*TiledLayer, error) {
+	layer := &TiledLayer{
+		Id: l.Id,
+		Name: l.Name,
+		Visible: l.Visible != "0",
+		Opacity: 1,
+		OffsetX: l.OffsetX,
+		OffsetY: l.OffsetY,
+		Width: l.Width,
+		Height: l.Height,
+	}
+
+	layer.Type = l.XMLName.Local
+	if layer.Type == "layer" {
+		layer.Type = TiledTileLayer
+	}
+
+	var err error
+	if l.Opacity != "" {
+		if layer.Opacity, err = strconv.ParseFloat(l.Opacity, 64); err != nil {
+			return nil, err
+		}
+	}
+	if layer.Properties, err = l.Properties.toTiled(); err != nil {
+		return nil, fmt.Errorf("Layer %q: %s", l.Name, err.Error())
+	}
+
+	if l.Data != nil {
+		switch l.Data.Encoding {
+			case "":
+				tiles := make([]uint32, len(l.Data.Tiles))
+				for i := range l.Data.Tiles {
+					tiles[i] = l.Data.Tiles[i].Gid
+				}
+				layer.Data, err = json.Marshal(tiles)
+			case "csv":
+				tiles := make([]uint32, 0, l.Width * l.Height)
+				for _, field := range strings.Split(l.Data.Text, ",") {
+					field = strings.TrimSpace(field)
+					if field == "" {
+						continue
+					}
+					gid, err := strconv.ParseUint(field, 10, 32)
+					if err != nil {
+						return nil, fmt.Errorf("Layer %q: %s", l.Name, err.Error())
+					}
+					tiles = append(tiles, uint32(gid))
+				}
+				layer.Data, err = json.Marshal(tiles)
+			default:
+				layer.Encoding = l.Data.Encoding
+				layer.Compression = l.Data.Compression
+				layer.Data, err = json.Marshal(strings.TrimSpace(l.Data.Text))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, obj := range l.Objects {
+		o := TiledObject{obj.Id, obj.Name, obj.Type, obj.Class, obj.X, obj.Y, obj.Width, obj.Height, obj.Gid, true, nil}
+		if o.Properties, err = obj.Properties.toTiled(); err != nil {
+			return nil, fmt.Errorf("Object %d: %s", obj.Id, err.Error())
+		}
+		layer.Objects = append(layer.Objects, o)
+	}
+
+	for i := range l.Layers {
+		if l.Layers[i].XMLName.Local == "properties" {
+			continue
+		}
+		child, err := l.Layers[i].toTiled()
+		if err != nil {
+			return nil, err
+		}
+		layer.Layers = append(layer.Layers, *child)
+	}
+
+	return layer, nil
+}
+
+func tmxFromTiled(tm *TiledMap) (*tmxMap, error) {
+	infinite := 0
+	if tm.Infinite {
+		infinite = 1
+	}
+	tmx := &tmxMap{
+		xml.Name{Local: "map"},
+		tm.Version,
+		tm.Orientation,
+		tm.RenderOrder,
+		tm.Width,
+		tm.Height,
+		tm.TileWidth,
+		tm.TileHeight,
+		infinite,
+		tm.NextLayerId,
+		tm.NextObjectId,
+		tmxPropertiesFromTiled(tm.Properties),
+		make([]tmxTileset, len(tm.Tilesets)),
+		make([]tmxLayer, 0, len(tm.Layers)),
+	}
+
+	for i, ts := range tm.Tilesets {
+		tmx.Tilesets[i] = tmxTileset{
+			FirstGid: ts.FirstGid,
+			Source: ts.Source,
+			Name: ts.Name,
+			TileWidth: ts.TileWidth,
+			TileHeight: ts.TileHeight,
+			Spacing: ts.Spacing,
+			Margin: ts.Margin,
+			TileCount: ts.TileCount,
+			Columns: ts.Columns,
+		}
+		if ts.Image != "" {
+			tmx.Tilesets[i].Image = &tmxImage{ts.Image, ts.ImageWidth, ts.ImageHeight}
+		}
+	}
+
+	for i := range tm.Layers {
+		layer, err := tmxLayerFromTiled(&tm.Layers[i])
+		if err != nil {
+			return nil, err
+		}
+		tmx.Layers = append(tmx.Layers, *layer)
+	}
+
+	return tmx, nil
+}
+
+func tmxLayerFromTiled(l *TiledLayer) (*tmxLayer, error) {
+	layer := &tmxLayer{
+		Id: l.Id,
+		Name: l.Name,
+		OffsetX: l.OffsetX,
+		OffsetY: l.OffsetY,
+		Properties: tmxPropertiesFromTiled(l.Properties),
+	}
+	if !l.Visible {
+		layer.Visible = "0"
+	}
+	if l.Opacity != 1 {
+		layer.Opacity = strconv.FormatFloat(l.Opacity, 'g', -1, 64)
+	}
+
+	switch l.Type {
+		case TiledTileLayer:
+			layer.XMLName.Local = "layer"
+			layer.Width = l.Width
+			layer.Height = l.Height
+			tiles, err := l.Tiles()
+			if err != nil {
+				return nil, err
+			}
+			fields := make([]string, len(tiles))
+			for i, gid := range tiles {
+				fields[i] = strconv.FormatUint(uint64(gid), 10)
+			}
+			layer.Data = &tmxData{Encoding: "csv", Text: strings.Join(fields, ",")}
+		case TiledObjectGroup:
+			layer.XMLName.Local = TiledObjectGroup
+			for _, obj := range l.Objects {
+				layer.Objects = append(layer.Objects, tmxObject{
+					obj.Id,
+					obj.Name,
+					obj.Type,
+					obj.Class,
+					obj.X,
+					obj.Y,
+					obj.Width,
+					obj.Height,
+					obj.Gid,
+					tmxPropertiesFromTiled(obj.Properties),
+				})
+			}
+		default:
+			return nil, errors.New("Only tile and object layers can be written as TMX")
+	}
+
+	return layer, nil
+}
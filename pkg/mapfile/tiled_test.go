@@ -0,0 +1,143 @@
+package mapfile
+
+import(
+	"encoding/json"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestImage(t *testing.T, path string, w, h int) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = png.Encode(file, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTiledRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestImage(t, filepath.Join(dir, "a.png"), 64, 32)
+	writeTestImage(t, filepath.Join(dir, "b.png"), 32, 32)
+
+	input := `{"FormatVersion":2,"Tiles":[[0,7,-1],[-1,2,3]],"Collision":[[true,false,false],[false,false,true]],"TextureIndicies":[[0,0,0],[0,1,1]],"Textures":["a.png","b.png"],"Exits":[{"Target":"b.json","Id":1,"X":2,"Y":0,"Z":1}],"Entries":[{"Id":3,"X":0,"Y":0,"Z":0}],"Width":3,"Height":1,"NpcInfo":[{"Texture":"npc.png","DialogPath":"hi.json","X":1,"Y":0,"Z":0,"MovementInfo":{"Strategy":1,"Commands":[0,1,2]}}],"Triggers":[{"Id":4,"Kind":2,"X":1,"Y":0,"Z":1,"W":2,"H":1,"DialogPath":"","Effect":"set_flag seen","Conditions":[{"Flag":"badges","Operation":">","Value":"2"},{"Flag":"met","Operation":"has","Value":""}],"Once":true},{"Id":5,"Kind":1,"X":0,"Y":0,"Z":0,"W":1,"H":1,"DialogPath":"sign.json","Effect":"","Conditions":[],"Once":false}],"Interactables":[{"X":2,"Y":0,"Z":0,"Facing":4,"DialogPath":"pc.json","Effect":""},{"X":0,"Y":0,"Z":1,"Facing":0,"DialogPath":"","Effect":"give_item potion 1"}],"Properties":{"Name":"ROUTE 1","Music":"route_1.mp3","Weather":1,"Indoor":true,"Encounters":"route_1.json"}}`
+
+	want := File{}
+	if err = json.Unmarshal([]byte(input), &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{TiledJSON, TiledTMX} {
+		tm, warnings, err := ToTiled([]byte(input), dir, "images/")
+		if err != nil || len(warnings) > 0 {
+			t.Fatalf("%s: %v %v", format, warnings, err)
+		}
+		if tm.Tilesets[1].FirstGid != 9 || tm.Tilesets[1].Image != "images/b.png" {
+			t.Errorf("%s: unexpected second tileset %v", format, tm.Tilesets[1])
+		}
+
+		data, err := EncodeTiled(tm, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if tm, err = DecodeTiled(data, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if data, warnings, err = FromTiled(tm); err != nil || len(warnings) > 0 {
+			t.Fatalf("%s: %v %v", format, warnings, err)
+		}
+
+		got := File{}
+		if err = json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", format, want, got)
+		}
+	}
+}
+
+func TestFromTiledWarnings(t *testing.T) {
+	tmx := `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.9" orientation="orthogonal" renderorder="right-down" width="2" height="1" tilewidth="16" tileheight="16" infinite="0">
+ <properties><property name="season" value="winter"/></properties>
+ <tileset firstgid="1" name="a" tilewidth="16" tileheight="16" tilecount="4" columns="2">
+  <image source="../images/a.png" width="32" height="32"/>
+ </tileset>
+ <layer id="1" name="ground" width="2" height="1">
+  <data encoding="base64" compression="zlib">eJxjZGBgYGFgaAAAAKAAhg==</data>
+ </layer>
+ <imagelayer id="2" name="sky"/>
+ <objectgroup id="3" name="things">
+  <object id="1" class="entry" x="16" y="4" width="16" height="16">
+   <properties><property name="id" type="int" value="2"/></properties>
+  </object>
+  <object id="2" type="sign" x="0" y="0"/>
+ </objectgroup>
+</map>`
+
+	tm, err := DecodeTiled([]byte(tmx), TiledTMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, warnings, err := FromTiled(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"season", "flipped", "sky", "not aligned", "sign", "1 tile layers but 0 collision"}
+	for _, e := range expected {
+		found := false
+		for _, w := range warnings {
+			found = found || strings.Contains(w, e)
+		}
+		if !found {
+			t.Errorf("Expected a warning about %q, got %v", e, warnings)
+		}
+	}
+
+	got := File{}
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Tiles, IntLayers{{0, 3}}) || got.Textures[0] != "a.png" {
+		t.Errorf("Unexpected tiles %v and textures %v", got.Tiles, got.Textures)
+	}
+	if len(got.Entries) != 1 || got.Entries[0] != (Entry{2, 1, 0, 0}) {
+		t.Errorf("Unexpected entries %v", got.Entries)
+	}
+}
+
+func TestFromTiledErrors(t *testing.T) {
+	inputs := []string{
+		`{"orientation":"isometric","width":1,"height":1,"tilewidth":16,"tileheight":16}`,
+		`{"orientation":"orthogonal","infinite":true,"width":1,"height":1,"tilewidth":16,"tileheight":16}`,
+		`{"orientation":"orthogonal","width":1,"height":1,"tilewidth":32,"tileheight":32}`,
+		`{"orientation":"orthogonal","width":1,"height":1,"tilewidth":16,"tileheight":16,"tilesets":[{"firstgid":1,"source":"a.tsx"}]}`,
+		`{"orientation":"orthogonal","width":2,"height":1,"tilewidth":16,"tileheight":16,"layers":[{"type":"tilelayer","name":"a","width":2,"height":1,"data":[1]}]}`,
+		`{"orientation":"orthogonal","width":1,"height":1,"tilewidth":16,"tileheight":16,"layers":[{"type":"objectgroup","name":"o","objects":[{"id":1,"type":"trigger","x":0,"y":0,"width":16,"height":16,"properties":[{"name":"conditions","type":"string","value":"seen"}]}]}]}`,
+	}
+
+	for _, input := range inputs {
+		tm, err := DecodeTiled([]byte(input), TiledJSON)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if _, _, err = FromTiled(tm); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
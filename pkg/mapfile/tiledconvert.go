@@ -0,0 +1,564 @@
+package mapfile
+
+import(
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tiles are square and this many pixels wide
+const TileSize = 16
+
+// Object types understood when importing from and exporting to Tiled
+const(
+	TiledExit = "exit"
+	TiledEntry = "entry"
+	TiledNpc = "npc"
+	TiledTrigger = "trigger"
+	TiledInteractable = "interactable"
+)
+
+// Tile layers with this boolean property set hold collision rather than
+// tiles, where any tile means that the tile is blocked
+const TiledCollisionProperty = "collision"
+
+// Warnings lists what could not be carried over when converting a map
+type Warnings []string
+
+func (w *Warnings) add(format string, args ...interface{}) {
+	*w = append(*w, fmt.Sprintf(format, args...))
+}
+
+// FromTiled converts a map made in Tiled into a tilemap. Tile layers become
+// the layers of the map in order and their tilesets its textures, collision
+// layers are applied to the layers in order, and objects of the types exit,
+// entry, npc, trigger and interactable are placed using their properties.
+// What cannot be converted is left out and listed in the returned warnings.
+func FromTiled(tm *TiledMap) ([]byte, Warnings, error) {
+	warnings := Warnings{}
+
+	if tm.Orientation != "" && tm.Orientation != "orthogonal" {
+		return nil, warnings, fmt.Errorf("Only orthogonal maps are supported, not %s", tm.Orientation)
+	}
+	if tm.Infinite {
+		return nil, warnings, errors.New("Infinite maps are not supported, resize the map to a fixed size")
+	}
+	if tm.TileWidth != TileSize || tm.TileHeight != TileSize {
+		return nil, warnings, fmt.Errorf("Tiles must be %dx%d, not %dx%d", TileSize, TileSize, tm.TileWidth, tm.TileHeight)
+	}
+	if tm.Width <= 0 || tm.Height <= 0 {
+		return nil, warnings, errors.New("Map has no size")
+	}
+
+	m := File{
+		FormatVersion,
+		"",
+		make([][]int, 0),
+		make([][]bool, 0),
+		make([][]int, 0),
+		make([]string, len(tm.Tilesets)),
+		make([]Exit, 0),
+		make([]Entry, 0),
+		tm.Width,
+		tm.Height,
+		make([]Npc, 0),
+		make([]Trigger, 0),
+		make([]Interactable, 0),
+		Properties{},
+	}
+
+	for _, prop := range tm.Properties {
+		var err error
+		switch prop.Name {
+			case "name":
+				m.Properties.Name = propertyString(tm.Properties, prop.Name)
+			case "music":
+				m.Properties.Music = propertyString(tm.Properties, prop.Name)
+			case "weather":
+				m.Properties.Weather, err = propertyInt(tm.Properties, prop.Name)
+			case "indoor":
+				m.Properties.Indoor = propertyBool(tm.Properties, prop.Name)
+			case "encounters":
+				m.Properties.Encounters = propertyString(tm.Properties, prop.Name)
+			default:
+				warnings.add("Map property %q is not one of name, music, weather, indoor or encounters, and is ignored", prop.Name)
+		}
+		if err != nil {
+			return nil, warnings, fmt.Errorf("Map property %q: %s", prop.Name, err.Error())
+		}
+	}
+
+	for i, ts := range tm.Tilesets {
+		if ts.Source != "" {
+			return nil, warnings, fmt.Errorf("Tileset %s is external, embed it in the map before importing", ts.Source)
+		}
+		if ts.Image == "" {
+			return nil, warnings, fmt.Errorf("Tileset %q is a collection of images, only tilesets made from one image are supported", ts.Name)
+		}
+		if ts.TileWidth != TileSize || ts.TileHeight != TileSize {
+			return nil, warnings, fmt.Errorf("Tileset %q must have %dx%d tiles", ts.Name, TileSize, TileSize)
+		}
+		if ts.Margin != 0 || ts.Spacing != 0 {
+			warnings.add("Tileset %q has margin or spacing, which the game does not, so its tiles will be misplaced", ts.Name)
+		}
+		if len(ts.Tiles) > 0 {
+			warnings.add("Tileset %q has per tile data such as properties or animations, which is ignored", ts.Name)
+		}
+		m.Textures[i] = path.Base(filepath.ToSlash(ts.Image))
+	}
+
+	nTiles := tm.Width * tm.Height
+	tileLayers := make([]*TiledLayer, 0)
+	collisionLayers := make([]*TiledLayer, 0)
+	objectLayers := make([]*TiledLayer, 0)
+	for i := range tm.Layers {
+		layer := &tm.Layers[i]
+		switch layer.Type {
+			case TiledTileLayer:
+				if layer.Width != tm.Width || layer.Height != tm.Height {
+					return nil, warnings, fmt.Errorf("Layer %q is %dx%d, not the %dx%d of the map", layer.Name, layer.Width, layer.Height, tm.Width, tm.Height)
+				}
+				if propertyBool(layer.Properties, TiledCollisionProperty) {
+					collisionLayers = append(collisionLayers, layer)
+				} else {
+					tileLayers = append(tileLayers, layer)
+				}
+			case TiledObjectGroup:
+				objectLayers = append(objectLayers, layer)
+			default:
+				warnings.add("Layer %q is of type %s, which is not supported, and is left out", layer.Name, layer.Type)
+				continue
+		}
+		if layer.OffsetX != 0 || layer.OffsetY != 0 {
+			warnings.add("Layer %q is offset, which is ignored", layer.Name)
+		}
+	}
+
+	for _, layer := range tileLayers {
+		gids, err := layer.Tiles()
+		if err != nil {
+			return nil, warnings, err
+		}
+		if len(gids) != nTiles {
+			return nil, warnings, fmt.Errorf("Layer %q holds %d tiles, not %d", layer.Name, len(gids), nTiles)
+		}
+
+		tiles := make([]int, nTiles)
+		indicies := make([]int, nTiles)
+		flipped, unknown := false, false
+		for i, gid := range gids {
+			if gid & tiledFlipMask != 0 {
+				flipped = true
+				gid &^= tiledFlipMask
+			}
+			tiles[i] = -1
+			if gid == 0 {
+				continue
+			}
+			set := tilesetOf(tm.Tilesets, gid)
+			if set == -1 {
+				unknown = true
+				continue
+			}
+			tiles[i] = int(gid) - tm.Tilesets[set].FirstGid
+			indicies[i] = set
+		}
+		if flipped {
+			warnings.add("Layer %q has flipped or rotated tiles, which are drawn unflipped", layer.Name)
+		}
+		if unknown {
+			warnings.add("Layer %q has tiles from no tileset, which are left out", layer.Name)
+		}
+
+		m.Tiles = append(m.Tiles, tiles)
+		m.TextureIndicies = append(m.TextureIndicies, indicies)
+	}
+
+	for _, layer := range collisionLayers {
+		gids, err := layer.Tiles()
+		if err != nil {
+			return nil, warnings, err
+		}
+		if len(gids) != nTiles {
+			return nil, warnings, fmt.Errorf("Layer %q holds %d tiles, not %d", layer.Name, len(gids), nTiles)
+		}
+		collision := make([]bool, nTiles)
+		for i, gid := range gids {
+			collision[i] = gid != 0
+		}
+		m.Collision = append(m.Collision, collision)
+	}
+
+	if len(m.Collision) != len(m.Tiles) {
+		warnings.add("Map has %d tile layers but %d collision layers, missing layers are filled in", len(m.Tiles), len(m.Collision))
+	}
+	for len(m.Tiles) < len(m.Collision) || len(m.Tiles) == 0 {
+		tiles := make([]int, nTiles)
+		for i := range tiles {
+			tiles[i] = -1
+		}
+		m.Tiles = append(m.Tiles, tiles)
+		m.TextureIndicies = append(m.TextureIndicies, make([]int, nTiles))
+	}
+	for len(m.Collision) < len(m.Tiles) {
+		m.Collision = append(m.Collision, make([]bool, nTiles))
+	}
+
+	for _, layer := range objectLayers {
+		for _, obj := range layer.Objects {
+			if err := m.addObject(tm, &obj, &warnings); err != nil {
+				return nil, warnings, fmt.Errorf("Object %d in layer %q: %s", obj.Id, layer.Name, err.Error())
+			}
+		}
+	}
+
+	data, err := json.Marshal(m)
+	return data, warnings, err
+}
+
+// tilesetOf returns the index of the tileset gid belongs to, or -1
+func tilesetOf(tilesets []TiledTileset, gid uint32) int {
+	for i := len(tilesets) - 1; i >= 0; i-- {
+		ts := &tilesets[i]
+		if int(gid) >= ts.FirstGid {
+			if ts.TileCount > 0 && int(gid) >= ts.FirstGid + ts.TileCount {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *File) addObject(tm *TiledMap, obj *TiledObject, warnings *Warnings) error {
+	kind := obj.Type
+	if kind == "" {
+		kind = obj.Class
+	}
+
+	top := obj.Y
+	if obj.Gid != 0 {
+		// Tile objects are placed by their lower left corner
+		top -= float64(tm.TileHeight)
+	}
+	x, y := int(obj.X) / TileSize, int(top) / TileSize
+	if int(obj.X) % TileSize != 0 || int(top) % TileSize != 0 {
+		warnings.add("Object %d is not aligned to the tile grid and is placed at tile (%d, %d)", obj.Id, x, y)
+	}
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		warnings.add("Object %d lies outside of the map and is left out", obj.Id)
+		return nil
+	}
+
+	z, err := propertyInt(obj.Properties, "z")
+	if err != nil {
+		return err
+	}
+	if z < 0 || z >= len(m.Tiles) {
+		return fmt.Errorf("Layer %d does not exist", z)
+	}
+
+	switch kind {
+		case TiledExit:
+			id, err := propertyInt(obj.Properties, "id")
+			if err != nil {
+				return err
+			}
+			target := propertyString(obj.Properties, "target")
+			if target == "" {
+				return errors.New("Exit has no target")
+			}
+			m.Exits = append(m.Exits, Exit{target, id, x, y, z})
+		case TiledEntry:
+			id, err := propertyInt(obj.Properties, "id")
+			if err != nil {
+				return err
+			}
+			m.Entries = append(m.Entries, Entry{id, x, y, z})
+		case TiledNpc:
+			npc := Npc{}
+			npc.Texture = propertyString(obj.Properties, "texture")
+			npc.DialogPath = propertyString(obj.Properties, "dialog")
+			npc.X, npc.Y, npc.Z = x, y, z
+			if npc.Texture == "" {
+				return errors.New("Npc has no texture")
+			}
+			if npc.MovementInfo.Strategy, err = propertyInt(obj.Properties, "strategy"); err != nil {
+				return err
+			}
+			npc.MovementInfo.Commands = make([]int, 0)
+			for _, field := range strings.Split(propertyString(obj.Properties, "commands"), ",") {
+				field = strings.TrimSpace(field)
+				if field == "" {
+					continue
+				}
+				command, err := strconv.Atoi(field)
+				if err != nil {
+					return fmt.Errorf("Bad movement command %q", field)
+				}
+				npc.MovementInfo.Commands = append(npc.MovementInfo.Commands, command)
+			}
+			m.NpcInfo = append(m.NpcInfo, npc)
+		case TiledTrigger:
+			tr := Trigger{}
+			if tr.Id, err = propertyInt(obj.Properties, "id"); err != nil {
+				return err
+			}
+			if tr.Kind, err = propertyInt(obj.Properties, "kind"); err != nil {
+				return err
+			}
+			tr.X, tr.Y, tr.Z = x, y, z
+			tr.W, tr.H = int(obj.Width) / TileSize, int(obj.Height) / TileSize
+			if int(obj.Width) % TileSize != 0 || int(obj.Height) % TileSize != 0 || tr.W < 1 || tr.H < 1 {
+				warnings.add("Trigger %d is not a whole number of tiles and covers %dx%d tiles", obj.Id, max(tr.W, 1), max(tr.H, 1))
+			}
+			tr.W, tr.H = max(tr.W, 1), max(tr.H, 1)
+			tr.DialogPath = propertyString(obj.Properties, "dialog")
+			tr.Effect = propertyString(obj.Properties, "effect")
+			tr.Once = propertyBool(obj.Properties, "once")
+			if tr.Conditions, err = ParseConditions(propertyString(obj.Properties, "conditions")); err != nil {
+				return err
+			}
+			m.Triggers = append(m.Triggers, tr)
+		case TiledInteractable:
+			in := Interactable{}
+			in.X, in.Y, in.Z = x, y, z
+			if in.Facing, err = propertyInt(obj.Properties, "facing"); err != nil {
+				return err
+			}
+			in.DialogPath = propertyString(obj.Properties, "dialog")
+			in.Effect = propertyString(obj.Properties, "effect")
+			m.Interactables = append(m.Interactables, in)
+		default:
+			warnings.add("Object %d is of type %q, which is not one of %s, %s, %s, %s or %s, and is left out", obj.Id, kind, TiledExit, TiledEntry, TiledNpc, TiledTrigger, TiledInteractable)
+	}
+	return nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ToTiled converts the tilemap held in data into a map for Tiled. The size of
+// each texture is read from the images directory, while the tilesets refer
+// to their images by prefixing them with imagePath.
+func ToTiled(data []byte, images, imagePath string) (*TiledMap, Warnings, error) {
+	warnings := Warnings{}
+
+	m := File{}
+	if _, err := m.Decode(data); err != nil {
+		return nil, warnings, err
+	}
+
+	tm := &TiledMap{
+		"map",
+		"1.4",
+		"orthogonal",
+		"right-down",
+		m.Width,
+		m.Height,
+		TileSize,
+		TileSize,
+		false,
+		1,
+		1,
+		make([]TiledLayer, 0, len(m.Tiles) * 2 + 1),
+		make([]TiledTileset, len(m.Textures)),
+		[]TiledProperty{
+			{"encounters", "string", m.Properties.Encounters},
+			{"indoor", "bool", m.Properties.Indoor},
+			{"music", "string", m.Properties.Music},
+			{"name", "string", m.Properties.Name},
+			{"weather", "int", m.Properties.Weather},
+		},
+	}
+
+	firstGid := 1
+	for i, texture := range m.Textures {
+		w, h, err := imageSize(filepath.Join(images, texture))
+		if err != nil {
+			return nil, warnings, err
+		}
+		name := strings.TrimSuffix(texture, path.Ext(texture))
+		columns := w / TileSize
+		tm.Tilesets[i] = TiledTileset{
+			FirstGid: firstGid,
+			Name: name,
+			TileWidth: TileSize,
+			TileHeight: TileSize,
+			TileCount: columns * (h / TileSize),
+			Columns: columns,
+			Image: imagePath + texture,
+			ImageWidth: w,
+			ImageHeight: h,
+		}
+		firstGid += tm.Tilesets[i].TileCount
+	}
+
+	nTiles := m.Width * m.Height
+	for z := range m.Tiles {
+		if len(m.Tiles[z]) != nTiles {
+			return nil, warnings, fmt.Errorf("Layer %d does not hold %d tiles", z, nTiles)
+		}
+		if z >= len(m.TextureIndicies) || len(m.TextureIndicies[z]) != nTiles {
+			return nil, warnings, fmt.Errorf("Layer %d does not have a texture index for each of its %d tiles", z, nTiles)
+		}
+		gids := make([]uint32, nTiles)
+		for i, n := range m.Tiles[z] {
+			if n < 0 {
+				continue
+			}
+			set := m.TextureIndicies[z][i]
+			if set < 0 || set >= len(tm.Tilesets) {
+				warnings.add("Tile %d in layer %d uses texture %d, which does not exist, and is left out", i, z, set)
+				continue
+			}
+			if n >= tm.Tilesets[set].TileCount {
+				warnings.add("Tile %d in layer %d lies outside of %s and is left out", i, z, m.Textures[set])
+				continue
+			}
+			gids[i] = uint32(tm.Tilesets[set].FirstGid + n)
+		}
+		tm.addTileLayer(fmt.Sprintf("layer %d", z), gids, nil)
+	}
+
+	if len(tm.Tilesets) == 0 && len(m.Collision) > 0 {
+		warnings.add("Map has no textures to draw collision with, collision is left out")
+		m.Collision = nil
+	}
+	for z := range m.Collision {
+		if len(m.Collision[z]) != nTiles {
+			return nil, warnings, fmt.Errorf("Collision layer %d does not hold %d tiles", z, nTiles)
+		}
+		// Collision is painted using the first tile of the first tileset
+		gids := make([]uint32, nTiles)
+		for i, blocked := range m.Collision[z] {
+			if blocked {
+				gids[i] = 1
+			}
+		}
+		properties := []TiledProperty{{TiledCollisionProperty, "bool", true}}
+		layer := tm.addTileLayer(fmt.Sprintf("collision %d", z), gids, properties)
+		layer.Visible = false
+		layer.Opacity = 0.5
+	}
+
+	objects := make([]TiledObject, 0)
+	area := func(kind string, x, y, w, h int, properties []TiledProperty) {
+		objects = append(objects, TiledObject{
+			tm.NextObjectId,
+			"",
+			kind,
+			"",
+			float64(x * TileSize),
+			float64(y * TileSize),
+			float64(w * TileSize),
+			float64(h * TileSize),
+			0,
+			true,
+			properties,
+		})
+		tm.NextObjectId++
+	}
+	object := func(kind string, x, y int, properties []TiledProperty) {
+		area(kind, x, y, 1, 1, properties)
+	}
+	for _, exit := range m.Exits {
+		object(TiledExit, exit.X, exit.Y, []TiledProperty{
+			{"id", "int", exit.Id},
+			{"target", "string", exit.Target},
+			{"z", "int", exit.Z},
+		})
+	}
+	for _, entry := range m.Entries {
+		object(TiledEntry, entry.X, entry.Y, []TiledProperty{
+			{"id", "int", entry.Id},
+			{"z", "int", entry.Z},
+		})
+	}
+	for _, npc := range m.NpcInfo {
+		commands := make([]string, len(npc.MovementInfo.Commands))
+		for i, command := range npc.MovementInfo.Commands {
+			commands[i] = strconv.Itoa(command)
+		}
+		object(TiledNpc, npc.X, npc.Y, []TiledProperty{
+			{"commands", "string", strings.Join(commands, ",")},
+			{"dialog", "string", npc.DialogPath},
+			{"strategy", "int", npc.MovementInfo.Strategy},
+			{"texture", "string", npc.Texture},
+			{"z", "int", npc.Z},
+		})
+	}
+	for _, tr := range m.Triggers {
+		area(TiledTrigger, tr.X, tr.Y, tr.W, tr.H, []TiledProperty{
+			{"conditions", "string", FormatConditions(tr.Conditions)},
+			{"dialog", "string", tr.DialogPath},
+			{"effect", "string", tr.Effect},
+			{"id", "int", tr.Id},
+			{"kind", "int", tr.Kind},
+			{"once", "bool", tr.Once},
+			{"z", "int", tr.Z},
+		})
+	}
+	for _, in := range m.Interactables {
+		object(TiledInteractable, in.X, in.Y, []TiledProperty{
+			{"dialog", "string", in.DialogPath},
+			{"effect", "string", in.Effect},
+			{"facing", "int", in.Facing},
+			{"z", "int", in.Z},
+		})
+	}
+
+	tm.Layers = append(tm.Layers, TiledLayer{
+		Id: tm.NextLayerId,
+		Name: "objects",
+		Type: TiledObjectGroup,
+		Visible: true,
+		Opacity: 1,
+		Objects: objects,
+	})
+	tm.NextLayerId++
+
+	return tm, warnings, nil
+}
+
+func (tm *TiledMap) addTileLayer(name string, gids []uint32, properties []TiledProperty) *TiledLayer {
+	data, _ := json.Marshal(gids)
+	tm.Layers = append(tm.Layers, TiledLayer{
+		Id: tm.NextLayerId,
+		Name: name,
+		Type: TiledTileLayer,
+		Visible: true,
+		Opacity: 1,
+		Width: tm.Width,
+		Height: tm.Height,
+		Data: data,
+		Properties: properties,
+	})
+	tm.NextLayerId++
+	return &tm.Layers[len(tm.Layers) - 1]
+}
+
+func imageSize(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return config.Width, config.Height, nil
+}
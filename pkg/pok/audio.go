@@ -8,13 +8,21 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
 	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"strings"
 )
 
 const volume = 0.2
 
+// Track played by maps which do not name one
+const defaultTrack = "route_1.mp3"
+
+// Skips the silence mp3 encoders pad the end of a track with when looping
+const mp3LoopTrim = 100000
+
 type Audio struct {
 	audioContext *audio.Context
 	audioPlayer *audio.Player
+	track string
 	thudPlayer *audio.Player
 	doorPlayer *audio.Player
 	playerJumpPlayer *audio.Player
@@ -40,18 +48,7 @@ func (a *Audio) PlayPlayerJump() {
 
 func NewAudio() Audio {
 	ctx := audio.NewContext(44100)
-	/*
-	msrc, err := loadMp3(ctx, constants.AudioDir + "apple.mp3")
-	debug.Assert(err)
-	loop := audio.NewInfiniteLoop(msrc, msrc.Length() - 2500000)
-	player, err := audio.NewPlayer(ctx, loop)
-	*/
-	msrc, err := loadMp3(ctx, constants.AudioDir + "route_1.mp3")
-	debug.Assert(err)
-	loop := audio.NewInfiniteLoop(msrc, msrc.Length() - 100000)
-	player, err := audio.NewPlayer(ctx, loop)
-	debug.Assert(err)
-	msrc, err = loadMp3(ctx, constants.AudioDir + "thud.mp3")
+	msrc, err := loadMp3(ctx, constants.AudioDir + "thud.mp3")
 	debug.Assert(err)
 	thud, err := audio.NewPlayer(ctx, msrc)
 	debug.Assert(err)
@@ -63,20 +60,60 @@ func NewAudio() Audio {
 	debug.Assert(err)
 	jump, err := audio.NewPlayer(ctx, osrc)
 
-	player.SetVolume(volume)
 	thud.SetVolume(volume)
 	door.SetVolume(volume)
 	jump.SetVolume(volume)
 
 	return Audio{
 		ctx,
-		player,
+		nil,
+		"",
 		thud,
 		door,
 		jump,
 	}
 }
 
+// PlayMusic loops track from the audio directory, or the default track if
+// track is empty. The music keeps playing if it already is the track.
+func (a *Audio) PlayMusic(track string) error {
+	if track == "" {
+		track = defaultTrack
+	}
+	if a.audioContext == nil || (track == a.track && a.audioPlayer != nil) {
+		return nil
+	}
+
+	var loop *audio.InfiniteLoop
+	if strings.HasSuffix(track, ".ogg") {
+		src, err := loadOgg(a.audioContext, constants.AudioDir + track)
+		if err != nil {
+			return err
+		}
+		loop = audio.NewInfiniteLoop(src, src.Length())
+	} else {
+		src, err := loadMp3(a.audioContext, constants.AudioDir + track)
+		if err != nil {
+			return err
+		}
+		loop = audio.NewInfiniteLoop(src, src.Length() - mp3LoopTrim)
+	}
+
+	player, err := audio.NewPlayer(a.audioContext, loop)
+	if err != nil {
+		return err
+	}
+
+	if a.audioPlayer != nil {
+		a.audioPlayer.Close()
+	}
+	a.audioPlayer = player
+	a.track = track
+	a.audioPlayer.SetVolume(volume)
+	a.audioPlayer.Play()
+	return nil
+}
+
 func loadMp3(ctx *audio.Context, str string) (*mp3.Stream, error) {
 	stream, err := ebitenutil.OpenFile(str)
 	if err != nil {
@@ -2,7 +2,7 @@ package pok
 
 import(
 	"github.com/atemmel/pok/pkg/constants"
-	"github.com/atemmel/pok/pkg/textures"
+	"github.com/atemmel/pok/pkg/tileset"
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
 )
@@ -304,48 +304,42 @@ func (c *Character) TryStep(dir Direction, g *Game) {
 }
 
 func (c *Character) TryJumpLedge(nx, ny int, g *Game) int {
-	if c.Z + 1 >= len(g.Ows.tileMap.Tiles) {
-		return DoNone
+	isDownLedge := func() bool {
+		return g.Ows.hasTag(nx, ny, c.Z + 1, tileset.LedgeDown)
 	}
 
-	//TODO: Check texture index as well
-	isDownLedge := func(i int) bool {
-		return textures.IsBase(g.Ows.tileMap.TextureIndicies[c.Z + 1][i]) && (g.Ows.tileMap.Tiles[c.Z + 1][i] == 213 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 214 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 215)
+	isRightLedge := func() bool {
+		return g.Ows.hasTag(nx, ny, c.Z + 1, tileset.LedgeRight)
 	}
 
-	isRightLedge := func(i int) bool {
-		return textures.IsBase(g.Ows.tileMap.TextureIndicies[c.Z + 1][i]) && (g.Ows.tileMap.Tiles[c.Z + 1][i] == 233 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 241 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 249)
+	isLeftLedge := func() bool {
+		return g.Ows.hasTag(nx, ny, c.Z + 1, tileset.LedgeLeft)
 	}
 
-	isLeftLedge := func(i int) bool {
-		return textures.IsBase(g.Ows.tileMap.TextureIndicies[c.Z + 1][i]) && (g.Ows.tileMap.Tiles[c.Z + 1][i] == 232 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 240 || g.Ows.tileMap.Tiles[c.Z + 1][i] == 248)
-	}
-
-	index := g.Ows.tileMap.Index(nx, ny)
-	if c.dir == Down && isDownLedge(index) {
+	if c.dir == Down && isDownLedge() {
 		if g.TileIsOccupied(nx, ny + 1, c.Z) {
 			return DoCollision
 		}
 		return DoJump
-	} else if c.dir != Down && isDownLedge(index) {
+	} else if c.dir != Down && isDownLedge() {
 		return DoCollision
 	}
 
-	if c.dir == Right && isRightLedge(index) {
+	if c.dir == Right && isRightLedge() {
 		if g.TileIsOccupied(nx + 1, ny, c.Z) {
 			return DoCollision
 		}
 		return DoJump
-	} else if c.dir != Right && isRightLedge(index) {
+	} else if c.dir != Right && isRightLedge() {
 		return DoCollision
 	}
 
-	if c.dir == Left && isLeftLedge(index) {
+	if c.dir == Left && isLeftLedge() {
 		if g.TileIsOccupied(nx - 1, ny, c.Z) {
 			return DoCollision
 		}
 		return DoJump
-	} else if c.dir != Left && isLeftLedge(index) {
+	} else if c.dir != Left && isLeftLedge() {
 		return DoCollision
 	}
 
@@ -353,11 +347,7 @@ func (c *Character) TryJumpLedge(nx, ny int, g *Game) int {
 }
 
 func (c *Character) CoordinateContainsWater(x, y int, g *Game) bool {
-	const innerWaterTile = 67
-	index := y * g.Ows.tileMap.Width + x
-	textureIndex := g.Ows.tileMap.textureMapping[g.Ows.tileMap.TextureIndicies[c.Z][index]]
-
-	return textures.IsWater(textureIndex) && g.Ows.tileMap.Tiles[c.Z][index] == innerWaterTile
+	return g.Ows.hasTag(x, y, c.Z, tileset.DeepWater)
 }
 
 func (c *Character) EndAnim() {
@@ -366,51 +356,29 @@ func (c *Character) EndAnim() {
 	c.isJumping = false
 }
 
+// isStairCase reports whether the layer above z holds a staircase at x, y
 func (c *Character) isStairCase(x, y, z int, g *Game) bool {
-	if len(g.Ows.tileMap.TextureIndicies) < z + 1 {
-		return false
-	}
-
-	stairBase := []int{
-		170,
-		192,
-		214,
-	}
-
-	index := g.Ows.tileMap.Index(x, y)
-	if textures.IsStair(g.Ows.tileMap.TextureIndicies[z + 1][index]) {
-		for _, i := range stairBase {
-			if g.Ows.tileMap.Tiles[z + 1][index] == i {
-				return false
-			}
-		}
-		return true
-	}
-	return false
+	return g.Ows.hasTag(x, y, z + 1, tileset.Stair)
 }
 
+// handleStairCase decides whether a step onto or off of a staircase moves up
+// or down from the tile the character stands on. Tiles which do not tell keep
+// the direction of the step before.
 func (c *Character) handleStairCase(g *Game) {
-	index := g.Ows.tileMap.Index(c.X, c.Y)
-
-	stairRightUp := []int{
-		170,
-		171,
-		192,
-		193,
-		214,
-		215,
+	upRight := g.Ows.hasTag(c.X, c.Y, c.Z + 1, tileset.StairUpRight)
+	upLeft := g.Ows.hasTag(c.X, c.Y, c.Z + 1, tileset.StairUpLeft)
+	if !upRight && !upLeft {
+		return
 	}
 
-	for _, i := range stairRightUp {
-		if g.Ows.tileMap.Tiles[c.Z + 1][index] == i {
-			c.isTraversingStaircaseUp = false
-			c.isTraversingStaircaseDown = false
-			switch c.dir {
-				case Right:
-					c.isTraversingStaircaseUp = true
-				case Left:
-					c.isTraversingStaircaseDown = true
-			}
-		}
+	c.isTraversingStaircaseUp = false
+	c.isTraversingStaircaseDown = false
+	switch c.dir {
+		case Right:
+			c.isTraversingStaircaseUp = upRight
+			c.isTraversingStaircaseDown = upLeft
+		case Left:
+			c.isTraversingStaircaseUp = upLeft
+			c.isTraversingStaircaseDown = upRight
 	}
 }
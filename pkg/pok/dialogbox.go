@@ -4,12 +4,14 @@ import (
 	"github.com/atemmel/pok/pkg/constants"
 	"github.com/atemmel/pok/pkg/debug"
 	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/fonts"
+	"github.com/atemmel/pok/pkg/locale"
 	"github.com/atemmel/pok/pkg/textures"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
-	"github.com/golang/freetype/truetype"
 	"golang.org/x/image/font"
-	"io/ioutil"
+	"golang.org/x/image/math/fixed"
+	"image"
 	"image/color"
 )
 
@@ -19,6 +21,13 @@ const (
 	textYDelta = 21
 )
 
+const (
+	choiceLineHeight = 16
+	choicePadding = 6
+	choiceCursorWidth = 10
+	choiceBorder = 2
+)
+
 const (
 	TextSlow = iota
 	TextNormal
@@ -26,36 +35,51 @@ const (
 	TextInstant
 )
 
+// textSpeeds maps the speeds named in dialog markup to those of the box
+var textSpeeds = map[string]int{
+	"slow": TextSlow,
+	"normal": TextNormal,
+	"fast": TextFast,
+	"instant": TextInstant,
+}
+
 var (
 	fgClr = color.RGBA{80, 80, 88, 255}
 	bgClr = color.RGBA{160, 160, 168, 255}
+	boxClr = color.RGBA{248, 248, 248, 255}
 )
 
+// glyph is a single letter of dialog along with how it is typed and drawn
+type glyph struct {
+	r rune
+	clr color.Color
+	speed int
+	pause int
+}
+
 type DialogBox struct {
 	Hidden bool
-	fullStr string
-	dispStr string
+	Vars dialog.VarResolver
+	glyphs []glyph
+	pages [][]dialog.Line
+	page int
+	shown int
+	paused int
 	font font.Face
 	box *ebiten.Image
 	speed int
 	ticks int
+	choices []string
+	choiceIndex int
+	choiceBox *ebiten.Image
 }
 
 func NewDialogBox() DialogBox {
-	data, err := ioutil.ReadFile(constants.FontsDir + "pokemon_pixel_font.ttf")
-	debug.Assert(err)
-
-	tt, err := truetype.Parse(data)
-	debug.Assert(err)
-
 	db := DialogBox{}
 
-	const dpi = 72
-	db.font = truetype.NewFace(tt, &truetype.Options{
-		Size: 16,
-		DPI: dpi,
-		Hinting: font.HintingFull,
-	})
+	var err error
+	db.font, err = fonts.LoadFont(constants.DialogFontPath, dialog.FontSize)
+	debug.Assert(err)
 
 	db.box, err = textures.LoadWithError(constants.ImagesDir + "dialog0.png")
 	debug.Assert(err)
@@ -66,41 +90,150 @@ func NewDialogBox() DialogBox {
 	return db
 }
 
+// SetString looks up str in the current locale, parses its markup, wraps it
+// to the width of the box and starts typing out the first page. Text with
+// broken markup is shown as it is written.
 func (d *DialogBox) SetString(str string) {
-	result := str
-	hasBreak := false
-	for i := range str {
-		if str[i] == '\n' {
-			hasBreak = true
-		}
+	str = locale.Text(str)
+	markup, err := dialog.ParseMarkup(str, d.Vars)
+	if err != nil {
+		markup = dialog.Markup{{Text: str}}
 	}
-	if !hasBreak && len(str) > dialog.MaxLetters {
-		index := dialog.MaxLetters
-		for i := dialog.MaxLetters; i > 0; i-- {
-			if result[i] == ' ' {
-				index = i
-				break
+
+	d.glyphs = d.glyphs[:0]
+	pause := 0
+	for _, span := range markup {
+		clr := color.Color(fgClr)
+		if c, ok := dialog.MarkupColors[span.Color]; ok {
+			clr = c
+		}
+		speed := d.speed
+		if s, ok := textSpeeds[span.Speed]; ok {
+			speed = s
+		}
+		pause += span.Pause
+		for _, r := range span.Text {
+			if r == '\r' {
+				continue
 			}
+			d.glyphs = append(d.glyphs, glyph{r, clr, speed, pause})
+			pause = 0
 		}
-		result = result[:index] + "\n" + result[index + 1:];
 	}
 
+	runes := make([]rune, len(d.glyphs))
+	for i := range d.glyphs {
+		runes[i] = d.glyphs[i].r
+	}
+	lines := dialog.Wrap(d.font, runes, dialog.TextWidth)
+	d.pages = dialog.Paginate(lines, dialog.LinesPerPage)
+	d.showPage(0)
+}
+
+func (d *DialogBox) showPage(page int) {
+	d.page = page
+	d.shown = d.pages[page][0].Start
 	d.ticks = 0
-	if d.speed == TextInstant {
-		d.dispStr = result
-	} else {
-		d.dispStr = ""
+	d.paused = 0
+	d.skipInstant()
+}
+
+func (d *DialogBox) pageEnd() int {
+	if len(d.pages) == 0 {
+		return 0
+	}
+	lines := d.pages[d.page]
+	return lines[len(lines) - 1].End
+}
+
+func (d *DialogBox) HasNextPage() bool {
+	return d.page + 1 < len(d.pages)
+}
+
+// NextPage starts typing out the next page of the current string
+func (d *DialogBox) NextPage() {
+	if d.HasNextPage() {
+		d.showPage(d.page + 1)
+	}
+}
+
+// SetChoices presents a menu of choices next to the dialog window once the
+// current string has been typed out
+func (d *DialogBox) SetChoices(choices []string) {
+	d.choices = make([]string, len(choices))
+	for i := range choices {
+		d.choices[i] = locale.Text(choices[i])
+	}
+	d.choiceIndex = 0
+
+	w := 0
+	for _, c := range d.choices {
+		if cw := font.MeasureString(d.font, c).Ceil(); cw > w {
+			w = cw
+		}
+	}
+
+	w += choiceCursorWidth + choicePadding * 2
+	h := len(choices) * choiceLineHeight + choicePadding * 2
+
+	d.choiceBox = ebiten.NewImage(w, h)
+	d.choiceBox.Fill(fgClr)
+	inner := image.Rect(choiceBorder, choiceBorder, w - choiceBorder, h - choiceBorder)
+	d.choiceBox.SubImage(inner).(*ebiten.Image).Fill(boxClr)
+}
+
+func (d *DialogBox) ClearChoices() {
+	d.choices = nil
+	d.choiceIndex = 0
+	d.choiceBox = nil
+}
+
+func (d *DialogBox) HasChoices() bool {
+	return len(d.choices) > 0
+}
+
+// MoveChoice moves the choice cursor by delta steps, wrapping around
+func (d *DialogBox) MoveChoice(delta int) {
+	if !d.HasChoices() {
+		return
+	}
+
+	d.choiceIndex = (d.choiceIndex + delta) % len(d.choices)
+	if d.choiceIndex < 0 {
+		d.choiceIndex += len(d.choices)
 	}
-	d.fullStr = result
 }
 
+// SelectChoice moves the choice cursor to index, if there is such a choice
+func (d *DialogBox) SelectChoice(index int) {
+	if index >= 0 && index < len(d.choices) {
+		d.choiceIndex = index
+	}
+}
+
+func (d *DialogBox) SelectedChoice() int {
+	return d.choiceIndex
+}
+
+// LastChoice is the choice picked when the player backs out of the menu
+func (d *DialogBox) LastChoice() int {
+	return len(d.choices) - 1
+}
+
+// IsDone reports whether the current page has been typed out
 func (d *DialogBox) IsDone() bool {
-	return len(d.dispStr) >= len(d.fullStr)
+	return d.shown >= d.pageEnd()
 }
 
 func (d *DialogBox) Update() {
 	if !d.Hidden && !d.IsDone() {
-		switch d.speed {
+		next := &d.glyphs[d.shown]
+		if d.paused < next.pause {
+			d.paused++
+			return
+		}
+
+		switch next.speed {
 			case TextSlow:
 				if d.ticks >= 3 {
 					d.nextChar()
@@ -113,14 +246,29 @@ func (d *DialogBox) Update() {
 				if d.ticks >= 1 {
 					d.nextChar()
 				}
+			case TextInstant:
+				d.nextChar()
 		}
 		d.ticks++
 	}
 }
 
 func (d *DialogBox) nextChar() {
-	d.dispStr = d.fullStr[:len(d.dispStr)+1]
+	d.shown++
 	d.ticks = 0
+	d.paused = 0
+	d.skipInstant()
+}
+
+// skipInstant shows every instant letter up until the next pause
+func (d *DialogBox) skipInstant() {
+	for d.shown < d.pageEnd() {
+		next := &d.glyphs[d.shown]
+		if next.speed != TextInstant || next.pause > 0 {
+			return
+		}
+		d.shown++
+	}
 }
 
 func (d *DialogBox) Draw(target *ebiten.Image) {
@@ -132,8 +280,78 @@ func (d *DialogBox) Draw(target *ebiten.Image) {
 	dy := constants.DisplaySizeY - d.box.Bounds().Dy() - 4
 	opt.GeoM.Translate(float64(dx), float64(dy))
 	target.DrawImage(d.box, opt)
-	text.Draw(target, d.dispStr, d.font, dx + textXDelta + 1, dy + textYDelta, bgClr)
-	text.Draw(target, d.dispStr, d.font, dx + textXDelta, dy + textYDelta + 1, bgClr)
-	text.Draw(target, d.dispStr, d.font, dx + textXDelta + 1, dy + textYDelta + 1, bgClr)
-	text.Draw(target, d.dispStr, d.font, dx + textXDelta, dy + textYDelta, fgClr)
+	d.drawGlyphs(target, dx + textXDelta, dy + textYDelta)
+
+	if d.HasChoices() && d.IsDone() && !d.HasNextPage() {
+		d.drawChoices(target, dx + d.box.Bounds().Dx(), dy)
+	}
+}
+
+// drawChoices draws the choice menu with its lower right corner resting on
+// the upper right corner of the dialog window
+func (d *DialogBox) drawChoices(target *ebiten.Image, right, bottom int) {
+	cx := right - d.choiceBox.Bounds().Dx()
+	cy := bottom - d.choiceBox.Bounds().Dy()
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Translate(float64(cx), float64(cy))
+	target.DrawImage(d.choiceBox, opt)
+
+	x := cx + choicePadding
+	y := cy + choicePadding + choiceLineHeight - 4
+	for i, c := range d.choices {
+		if i == d.choiceIndex {
+			d.drawText(target, ">", x, y)
+		}
+		d.drawText(target, c, x + choiceCursorWidth, y)
+		y += choiceLineHeight
+	}
+}
+
+// drawGlyphs draws the lines of the current page typed so far, one run of
+// equal color at a time
+func (d *DialogBox) drawGlyphs(target *ebiten.Image, x, y int) {
+	lineHeight := d.font.Metrics().Height
+	run := make([]rune, 0, dialog.MaxLetters)
+
+	for i, line := range d.pages[d.page] {
+		runX := x
+		runY := y + (lineHeight * fixed.Int26_6(i)).Round()
+		var runClr color.Color = fgClr
+
+		flush := func() {
+			if len(run) > 0 {
+				str := string(run)
+				d.drawColoredText(target, str, runX, runY, runClr)
+				runX += font.MeasureString(d.font, str).Round()
+				run = run[:0]
+			}
+		}
+
+		end := line.End
+		if d.shown < end {
+			end = d.shown
+		}
+		if end < line.Start {
+			break
+		}
+		for _, g := range d.glyphs[line.Start:end] {
+			if g.clr != runClr {
+				flush()
+				runClr = g.clr
+			}
+			run = append(run, g.r)
+		}
+		flush()
+	}
+}
+
+func (d *DialogBox) drawText(target *ebiten.Image, str string, x, y int) {
+	d.drawColoredText(target, str, x, y, fgClr)
+}
+
+func (d *DialogBox) drawColoredText(target *ebiten.Image, str string, x, y int, clr color.Color) {
+	text.Draw(target, str, d.font, x + 1, y, bgClr)
+	text.Draw(target, str, d.font, x, y + 1, bgClr)
+	text.Draw(target, str, d.font, x + 1, y + 1, bgClr)
+	text.Draw(target, str, d.font, x, y, clr)
 }
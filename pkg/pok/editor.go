@@ -8,6 +8,8 @@ import (
 	"github.com/atemmel/pok/pkg/fonts"
 	"github.com/atemmel/pok/pkg/textures"
 	"github.com/atemmel/pok/pkg/jobs"
+	"github.com/atemmel/pok/pkg/mapfile"
+	"github.com/atemmel/pok/pkg/world"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -38,6 +40,19 @@ var drawUi = false
 var activeTool = Pencil
 var placedObjects [][]PlacedEditorObject = make([][]PlacedEditorObject, 0)
 var linkBegin *LinkData
+var triggerBegin *LinkData
+var triggerEndX, triggerEndY int
+var activeTriggerKind = StepTrigger
+var activeFacing = Static
+
+// Names of the ways an interactable may be faced, in the order of Direction
+var FacingNames = []string{
+	"Any",
+	"Down",
+	"Left",
+	"Right",
+	"Up",
+}
 var lastSavedUndoStackLength = 0
 
 var treeArea = &TreeAreaSelection{}
@@ -67,6 +82,8 @@ const(
 	AutoTile
 	Tree
 	PlaceNpc
+	PlaceTrigger
+	PlaceInteractable
 	NIcons
 )
 
@@ -79,6 +96,8 @@ var ToolNames = [NIcons]string{
 	"Autotile",
 	"Tree",
 	"Npc",
+	"Trigger",
+	"Interactable",
 }
 
 type Vec2 struct {
@@ -113,6 +132,8 @@ type Editor struct {
 	npcImages []*ebiten.Image
 	npcImagesStrings []string
 	npcGrid NpcGrid
+	typewriter Typewriter
+	world *world.World
 	dieOnNextTick bool
 }
 
@@ -193,6 +214,9 @@ func NewEditor(paths []string) *Editor {
 	es.tileMaps = make([]*TileMap, 0)
 	es.tileMapOffsets = make([]*Vec2, 0)
 
+	es.world, err = world.Read(constants.WorldPath)
+	debug.Assert(err)
+
 	es.npcImagesStrings = listPngs(constants.CharacterImagesDir)
 	es.npcImages = loadImages(es.npcImagesStrings, constants.CharacterImagesDir)
 	es.npcGrid = NewNpcGrid(es.npcImages)
@@ -311,9 +335,79 @@ func NewEditor(paths []string) *Editor {
 		X: IconOffsetX + 16 * 16, Y: constants.DisplaySizeY - 20,
 	})
 
+	AddButton(&ButtonInfo{
+		Content: "OPEN WORLD",
+		OnClick: func() {
+			es.openWorld()
+		},
+		VisibilityCondition: nil,
+		X: IconOffsetX + 16 * 20, Y: constants.DisplaySizeY - 20,
+	})
+
+	hasMap := func() bool {
+		return es.activeTileMap != nil
+	}
+
+	AddButton(&ButtonInfo{
+		Content: "NAME",
+		OnClick: func() {
+			es.typewriter.Start("Map name: ", func(name string) {
+				props := es.activeTileMap.Properties
+				props.Name = name
+				es.setMapProperties(props)
+			})
+		},
+		VisibilityCondition: hasMap,
+		X: IconOffsetX + 16 * 13, Y: constants.DisplaySizeY - 20 - 20,
+	})
+
+	AddButton(&ButtonInfo{
+		Content: "MUSIC",
+		OnClick: func() {
+			props := es.activeTileMap.Properties
+			props.Music = es.pickFile("Select map music", constants.AudioDir)
+			es.setMapProperties(props)
+		},
+		VisibilityCondition: hasMap,
+		X: IconOffsetX + 16 * 15 + 2, Y: constants.DisplaySizeY - 20 - 20,
+	})
+
+	AddButton(&ButtonInfo{
+		Content: "WEATHER",
+		OnClick: func() {
+			props := es.activeTileMap.Properties
+			props.Weather = (props.Weather + 1) % NWeathers
+			es.setMapProperties(props)
+		},
+		VisibilityCondition: hasMap,
+		X: IconOffsetX + 16 * 17 + 8, Y: constants.DisplaySizeY - 20 - 20,
+	})
+
+	AddButton(&ButtonInfo{
+		Content: "INDOOR",
+		OnClick: func() {
+			props := es.activeTileMap.Properties
+			props.Indoor = !props.Indoor
+			es.setMapProperties(props)
+		},
+		VisibilityCondition: hasMap,
+		X: IconOffsetX + 16 * 21, Y: constants.DisplaySizeY - 20 - 20,
+	})
+
+	AddButton(&ButtonInfo{
+		Content: "ENCOUNTERS",
+		OnClick: func() {
+			props := es.activeTileMap.Properties
+			props.Encounters = es.pickFile("Select encounter table", constants.ResourceDir)
+			es.setMapProperties(props)
+		},
+		VisibilityCondition: hasMap,
+		X: IconOffsetX + 16 * 24, Y: constants.DisplaySizeY - 20 - 20,
+	})
+
 	jobs.Add(jobs.Job{
-		Do: WaterAnim,
-		When: 11,
+		Do: AnimateTiles,
+		When: 1,
 	})
 
 	jobs.Add(jobs.Job{
@@ -378,6 +472,30 @@ func (e *Editor) Draw(screen *ebiten.Image) {
 x: %f, y: %f, z: %d
 zoom: %d%%
 %s`, e.rend.Cam.X, e.rend.Cam.Y, currentLayer, int(e.rend.Cam.Scale * 100), ToolNames[activeTool])
+	if activeTool == PlaceTrigger {
+		debugStr += " (" + TriggerKindNames[activeTriggerKind] + ")"
+		if len(e.activeFiles) != 0 {
+			if i := e.activeTileMap.TriggerAt(selectionX, selectionY, currentLayer); i != -1 {
+				debugStr += "\n" + describeTrigger(&e.activeTileMap.Triggers[i])
+			}
+		}
+	} else if activeTool == PlaceInteractable {
+		debugStr += " (facing " + FacingNames[activeFacing] + ")"
+		if len(e.activeFiles) != 0 {
+			if i := e.activeTileMap.InteractableAt(selectionX, selectionY, currentLayer); i != -1 {
+				debugStr += "\n" + describeInteractable(&e.activeTileMap.Interactables[i])
+			}
+		}
+	}
+	if len(e.activeFiles) != 0 {
+		props := &e.activeTileMap.Properties
+		debugStr += fmt.Sprintf(`
+name: %s, music: %s
+weather: %s, indoor: %t, encounters: %s`, props.Name, props.Music, WeatherNames[props.Weather], props.Indoor, props.Encounters)
+	}
+	if e.typewriter.Active {
+		debugStr += "\n" + e.typewriter.GetDisplayString()
+	}
 	ebitenutil.DebugPrint(screen, debugStr)
 }
 
@@ -441,6 +559,12 @@ func (e *Editor) DrawTileMapDetail() {
 			})
 		}
 
+		if activeTool == PlaceTrigger {
+			e.drawTriggers(offset)
+		} else if activeTool == PlaceInteractable {
+			e.drawInteractables(offset)
+		}
+
 		if activeTool == Eraser {
 			for i := range placedObjects[e.activeTileMapIndex] {
 				e.rend.Draw(&RenderTarget{
@@ -526,8 +650,15 @@ func (e *Editor) newFile() {
 
 func (e *Editor) updateEditorWithNewTileMap(tileMap *TileMap) {
 	e.appendTileMap(tileMap)
-	e.activeFullFiles = append(e.activeFiles, e.nextFile)
+	e.activeFullFiles = append(e.activeFullFiles, e.nextFile)
 	e.activeFiles = append(e.activeFiles, filepath.Base(e.nextFile))
+	if name, ok := worldName(e.nextFile); ok {
+		if i := e.world.Find(name); i != -1 {
+			offset := e.tileMapOffsets[len(e.tileMapOffsets) - 1]
+			offset.X = float64(e.world.Maps[i].X * constants.TileSize)
+			offset.Y = float64(e.world.Maps[i].Y * constants.TileSize)
+		}
+	}
 	drawUi = true
 	const baseIndex = 0
 	e.grid = NewGrid(textures.Access(tileMap.textureMapping[activePalette]), constants.TileSize)
@@ -561,9 +692,55 @@ func (e *Editor) saveFile() {
 		return
 	}
 
+	if err = e.saveWorld(); err != nil {
+		dialog.Message("Could not save world %s, %s", constants.WorldPath, err.Error()).Title("Error").Error()
+		return
+	}
+
 	lastSavedUndoStackLength = len(UndoStack)
 }
 
+// saveWorld places every open map of the tilemap directory in the world where
+// it lies on the canvas
+func (e *Editor) saveWorld() error {
+	for i, file := range e.activeFullFiles {
+		name, ok := worldName(file)
+		if !ok {
+			continue
+		}
+		e.world.Place(world.Placement{
+			Map: name,
+			X: int(math.Round(e.tileMapOffsets[i].X / constants.TileSize)),
+			Y: int(math.Round(e.tileMapOffsets[i].Y / constants.TileSize)),
+			Width: e.tileMaps[i].Width,
+			Height: e.tileMaps[i].Height,
+		})
+	}
+	return e.world.Write(constants.WorldPath)
+}
+
+// openWorld opens every map of the world which is not already open
+func (e *Editor) openWorld() {
+	open := make(map[string]bool)
+	for _, file := range e.activeFullFiles {
+		if name, ok := worldName(file); ok {
+			open[name] = true
+		}
+	}
+
+	for _, p := range e.world.Maps {
+		if open[p.Map] {
+			continue
+		}
+		tm, err := e.loadFile(constants.TileMapDir + p.Map)
+		if err != nil {
+			dialog.Message("Could not open file %s, %s", p.Map, err.Error()).Title("Error").Error()
+			continue
+		}
+		e.updateEditorWithNewTileMap(tm)
+	}
+}
+
 func (e *Editor) hasSaved() bool {
 	return len(UndoStack) == lastSavedUndoStackLength
 }
@@ -579,6 +756,11 @@ func (e *Editor) handleInputs() error {
 		return errors.New("")
 	}
 
+	if e.typewriter.Active {
+		e.typewriter.HandleInputs()
+		return nil
+	}
+
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		if !e.hasSaved() {
 			e.unsavedWorkDialog()
@@ -721,6 +903,14 @@ func (e *Editor) handleMapInputs() {
 	if inpututil.IsKeyJustPressed(ebiten.KeyU) {
 		drawOnlyCurrentLayer = !drawOnlyCurrentLayer
 	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		if activeTool == PlaceTrigger {
+			activeTriggerKind = (activeTriggerKind + 1) % NTriggerKinds
+		} else if activeTool == PlaceInteractable {
+			activeFacing = (activeFacing + 1) % Direction(len(FacingNames))
+		}
+	}
 }
 
 func (e *Editor) handleMapMouseInputs() {
@@ -760,6 +950,8 @@ func (e *Editor) handleMapMouseInputs() {
 						//TODO: perform tree logic
 						treeArea.TreeInfo = &e.treeAutoTileInfo[e.treeAutoTileGrid.GetIndex()]
 						treeArea.Hold(selectionX, selectionY)
+					case PlaceTrigger:
+						e.holdTrigger()
 				}
 			}
 		}
@@ -783,6 +975,8 @@ func (e *Editor) handleMapMouseInputs() {
 					e.doLink()
 				case PlaceNpc:
 					e.doPlaceNpc()
+				case PlaceInteractable:
+					e.doPlaceInteractable()
 			}
 		}
 	}
@@ -798,6 +992,10 @@ func (e *Editor) handleMapMouseInputs() {
 					e.doRemoveLink()
 				case PlaceNpc:
 					e.doRemoveNpc()
+				case PlaceTrigger:
+					e.doRemoveTrigger()
+				case PlaceInteractable:
+					e.doRemoveInteractable()
 			}
 		}
 	}
@@ -844,6 +1042,11 @@ func (e *Editor) handleMapMouseInputs() {
 				e.postDoAutotile()
 			case PlaceNpc:
 				e.postDoNpc()
+			case PlaceTrigger:
+				e.doPlaceTrigger()
+				e.postDoTrigger()
+			case PlaceInteractable:
+				e.postDoInteractable()
 			case Tree:
 				treeArea.Release(e.activeTileMap, currentLayer)
 		}
@@ -863,6 +1066,10 @@ func (e *Editor) handleMapMouseInputs() {
 				e.postDoRemoveLink()
 			case PlaceNpc:
 				e.postDoRemoveNpc()
+			case PlaceTrigger:
+				e.postDoRemoveTrigger()
+			case PlaceInteractable:
+				e.postDoRemoveInteractable()
 		}
 	}
 }
@@ -984,6 +1191,7 @@ func (e *Editor) getTileMapIndexAtCoord(cx, cy int) int {
 func (e *Editor) switchActiveTool(newTool int) {
 	activeTool = newTool
 	linkBegin = nil
+	triggerBegin = nil
 }
 
 func (e *Editor) npcAtPosition(x, y int) bool {
@@ -1049,33 +1257,33 @@ func (e *Editor) tryConnectTileMaps(start, end *LinkData) {
 	}
 
 	entryA := Entry{
-		startEntryIndex,
-		start.X,
-		start.Y,
-		currentLayer,
+		Id: startEntryIndex,
+		X: start.X,
+		Y: start.Y,
+		Z: currentLayer,
 	}
 
 	exitA := Exit{
-		e.activeFiles[end.TileMapIndex],
-		endEntryIndex,
-		start.X,
-		start.Y,
-		currentLayer,
+		Target: e.activeFiles[end.TileMapIndex],
+		Id: endEntryIndex,
+		X: start.X,
+		Y: start.Y,
+		Z: currentLayer,
 	}
 
 	entryB := Entry{
-		endEntryIndex,
-		end.X,
-		end.Y,
-		currentLayer,
+		Id: endEntryIndex,
+		X: end.X,
+		Y: end.Y,
+		Z: currentLayer,
 	}
 
 	exitB := Exit{
-		e.activeFiles[start.TileMapIndex],
-		startEntryIndex,
-		end.X,
-		end.Y,
-		currentLayer,
+		Target: e.activeFiles[start.TileMapIndex],
+		Id: startEntryIndex,
+		X: end.X,
+		Y: end.Y,
+		Z: currentLayer,
 	}
 
 	e.tileMaps[start.TileMapIndex].PlaceEntry(entryA)
@@ -1626,7 +1834,10 @@ func (e *Editor) doPlaceNpc() {
 			NpcMovementInfo{},
 		}
 
-		e.activeTileMap.PlaceNpc(ni)
+		if err = e.activeTileMap.PlaceNpc(ni); err != nil {
+			dialog.Message("Could not place npc: %s", err.Error()).Title("Error").Error()
+			return
+		}
 
 		CurrentNpcDelta.npcInfo = ni
 		CurrentNpcDelta.npcIndex = len(e.activeTileMap.npcs) -1
@@ -1662,6 +1873,353 @@ func (e *Editor) doRemoveNpc() {
 	CurrentRemoveNpcDelta.npcDelta = nd
 }
 
+func (e *Editor) postDoTrigger() {
+	if CurrentTriggerDelta.trigger == nil {
+		return
+	}
+
+	UndoStack = append(UndoStack, CurrentTriggerDelta)
+	CurrentTriggerDelta = &TriggerDelta{}
+}
+
+func (e *Editor) postDoRemoveTrigger() {
+	if CurrentRemoveTriggerDelta.triggerDelta == nil {
+		return
+	}
+
+	UndoStack = append(UndoStack, CurrentRemoveTriggerDelta)
+	CurrentRemoveTriggerDelta = &RemoveTriggerDelta{}
+}
+
+// holdTrigger stretches the area of the trigger being placed to the selected
+// tile
+func (e *Editor) holdTrigger() {
+	if triggerBegin == nil || triggerBegin.TileMapIndex != e.activeTileMapIndex {
+		triggerBegin = &LinkData{
+			selectionX,
+			selectionY,
+			e.activeTileMapIndex,
+		}
+	}
+	triggerEndX, triggerEndY = selectionX, selectionY
+}
+
+// triggerArea returns the tiles covered by the trigger being placed
+func triggerArea() (int, int, int, int) {
+	x, y := triggerBegin.X, triggerBegin.Y
+	w, h := abs(triggerEndX - x) + 1, abs(triggerEndY - y) + 1
+	if triggerEndX < x {
+		x = triggerEndX
+	}
+	if triggerEndY < y {
+		y = triggerEndY
+	}
+	return x, y, w, h
+}
+
+func (e *Editor) doPlaceTrigger() {
+	if triggerBegin == nil {
+		return
+	}
+	x, y, w, h := triggerArea()
+	triggerBegin = nil
+
+	tr := &Trigger{
+		e.activeTileMap.NextTriggerId(),
+		activeTriggerKind,
+		x, y, currentLayer,
+		w, h,
+		"",
+		"",
+		make([]TriggerCondition, 0),
+		false,
+	}
+
+	useEffect := dialog.Message("Should the trigger perform an effect instead of showing a dialog?").Title("Perform effect?").YesNo()
+	if !useEffect {
+		file, err := dialog.File().Title("Select trigger dialog file").Filter("All Files", "*").Load()
+		os.Chdir(WorkingDir)
+		if err != nil && file == "" {
+			return
+		} else if err != nil {
+			dialog.Message("Could not open file: %s", file).Title("Error").Error()
+			return
+		}
+		tr.DialogPath = filepath.Base(file)
+	}
+
+	tr.Once = dialog.Message("Should the trigger only fire once?").Title("Fire once?").YesNo()
+
+	// The rest is typed in, after which the trigger is placed on the map it
+	// was drawn on
+	index := e.activeTileMapIndex
+	askConditions := func() {
+		e.typewriter.Start("Conditions (flag op value; ...): ", func(str string) {
+			conditions, err := mapfile.ParseConditions(str)
+			if err != nil {
+				dialog.Message("Could not read conditions: %s", err.Error()).Title("Error").Error()
+				return
+			}
+			tr.Conditions = conditions
+			e.placeTrigger(tr, index)
+		})
+	}
+
+	if !useEffect {
+		askConditions()
+		return
+	}
+	e.typewriter.Start("Effect and arguments: ", func(effect string) {
+		if effect == "" {
+			return
+		}
+		tr.Effect = effect
+		askConditions()
+	})
+}
+
+// placeTrigger places tr on the map at index, recording it for undoing
+func (e *Editor) placeTrigger(tr *Trigger, index int) {
+	if err := e.tileMaps[index].PlaceTrigger(tr); err != nil {
+		dialog.Message("Could not place trigger: %s", err.Error()).Title("Error").Error()
+		return
+	}
+
+	CurrentTriggerDelta.trigger = tr
+	CurrentTriggerDelta.triggerIndex = len(e.tileMaps[index].Triggers) - 1
+	CurrentTriggerDelta.tileMapIndex = index
+	e.postDoTrigger()
+	RedoStack = RedoStack[:0]
+}
+
+// describeTrigger tells what tr does, for showing the selected trigger
+func describeTrigger(tr *Trigger) string {
+	str := fmt.Sprintf("trigger %d: ", tr.Id)
+	if tr.Effect != "" {
+		str += "effect " + tr.Effect
+	} else {
+		str += "dialog " + tr.DialogPath
+	}
+	if len(tr.Conditions) > 0 {
+		str += ", if " + mapfile.FormatConditions(tr.Conditions)
+	}
+	if tr.Once {
+		str += ", once"
+	}
+	return str
+}
+
+func (e *Editor) doRemoveTrigger() {
+	index := e.activeTileMap.TriggerAt(selectionX, selectionY, currentLayer)
+	if index == -1 {
+		return
+	}
+
+	tr := e.activeTileMap.Triggers[index]
+
+	td := &TriggerDelta{
+		&tr,
+		index,
+		e.activeTileMapIndex,
+	}
+
+	e.activeTileMap.RemoveTrigger(index)
+
+	CurrentRemoveTriggerDelta.triggerDelta = td
+}
+
+// drawTriggers outlines the triggers of the current layer along with the one
+// being placed
+func (e *Editor) drawTriggers(offset *Vec2) {
+	clr := color.RGBA{255, 200, 0, 255}
+	for _, tr := range e.activeTileMap.Triggers {
+		if tr.Z == currentLayer {
+			e.drawTileRect(tr.X, tr.Y, tr.W, tr.H, offset, clr)
+		}
+	}
+
+	if triggerBegin != nil && triggerBegin.TileMapIndex == e.activeTileMapIndex {
+		x, y, w, h := triggerArea()
+		e.drawTileRect(x, y, w, h, offset, color.RGBA{255, 0, 0, 255})
+	}
+}
+
+func (e *Editor) drawTileRect(x, y, w, h int, offset *Vec2, clr color.RGBA) {
+	x0 := float64(x * constants.TileSize) + offset.X
+	y0 := float64(y * constants.TileSize) + offset.Y
+	x1 := x0 + float64(w * constants.TileSize)
+	y1 := y0 + float64(h * constants.TileSize)
+
+	e.rend.DrawLine(DebugLine{x0, y0, x1, y0, clr})
+	e.rend.DrawLine(DebugLine{x0, y1, x1, y1, clr})
+	e.rend.DrawLine(DebugLine{x0, y0, x0, y1, clr})
+	e.rend.DrawLine(DebugLine{x1, y0, x1, y1, clr})
+}
+
+func (e *Editor) postDoInteractable() {
+	if CurrentInteractableDelta.interactable == nil {
+		return
+	}
+
+	UndoStack = append(UndoStack, CurrentInteractableDelta)
+	CurrentInteractableDelta = &InteractableDelta{}
+}
+
+func (e *Editor) postDoRemoveInteractable() {
+	if CurrentRemoveInteractableDelta.interactableDelta == nil {
+		return
+	}
+
+	UndoStack = append(UndoStack, CurrentRemoveInteractableDelta)
+	CurrentRemoveInteractableDelta = &RemoveInteractableDelta{}
+}
+
+func (e *Editor) doPlaceInteractable() {
+	if e.activeTileMap.InteractableAt(selectionX, selectionY, currentLayer) != -1 {
+		return
+	}
+
+	in := &Interactable{
+		selectionX,
+		selectionY,
+		currentLayer,
+		activeFacing,
+		"",
+		"",
+	}
+
+	useEffect := dialog.Message("Should the interactable perform an effect instead of showing a dialog?").Title("Perform effect?").YesNo()
+	if !useEffect {
+		file, err := dialog.File().Title("Select interactable dialog file").Filter("All Files", "*").Load()
+		os.Chdir(WorkingDir)
+		if err != nil && file == "" {
+			return
+		} else if err != nil {
+			dialog.Message("Could not open file: %s", file).Title("Error").Error()
+			return
+		}
+		in.DialogPath = filepath.Base(file)
+		e.placeInteractable(in, e.activeTileMapIndex)
+		return
+	}
+
+	index := e.activeTileMapIndex
+	e.typewriter.Start("Effect and arguments: ", func(effect string) {
+		if effect == "" {
+			return
+		}
+		in.Effect = effect
+		e.placeInteractable(in, index)
+	})
+}
+
+// placeInteractable places in on the map at index, recording it for undoing
+func (e *Editor) placeInteractable(in *Interactable, index int) {
+	if err := e.tileMaps[index].PlaceInteractable(in); err != nil {
+		dialog.Message("Could not place interactable: %s", err.Error()).Title("Error").Error()
+		return
+	}
+
+	CurrentInteractableDelta.interactable = in
+	CurrentInteractableDelta.interactableIndex = len(e.tileMaps[index].Interactables) - 1
+	CurrentInteractableDelta.tileMapIndex = index
+	e.postDoInteractable()
+	RedoStack = RedoStack[:0]
+}
+
+// describeInteractable tells what in does, for showing the selected
+// interactable
+func describeInteractable(in *Interactable) string {
+	str := "interactable (facing " + FacingNames[in.Facing] + "): "
+	if in.Effect != "" {
+		return str + "effect " + in.Effect
+	}
+	return str + "dialog " + in.DialogPath
+}
+
+func (e *Editor) doRemoveInteractable() {
+	index := e.activeTileMap.InteractableAt(selectionX, selectionY, currentLayer)
+	if index == -1 {
+		return
+	}
+
+	in := e.activeTileMap.Interactables[index]
+
+	id := &InteractableDelta{
+		&in,
+		index,
+		e.activeTileMapIndex,
+	}
+
+	e.activeTileMap.RemoveInteractable(index)
+
+	CurrentRemoveInteractableDelta.interactableDelta = id
+}
+
+// drawInteractables outlines the interactables of the current layer, marking
+// the side they are used from
+func (e *Editor) drawInteractables(offset *Vec2) {
+	clr := color.RGBA{0, 200, 255, 255}
+	for _, in := range e.activeTileMap.Interactables {
+		if in.Z != currentLayer {
+			continue
+		}
+		e.drawTileRect(in.X, in.Y, 1, 1, offset, clr)
+
+		// The player stands on the opposite side of the way they face
+		x0 := float64(in.X * constants.TileSize) + offset.X
+		y0 := float64(in.Y * constants.TileSize) + offset.Y
+		x1 := x0 + constants.TileSize
+		y1 := y0 + constants.TileSize
+		switch in.Facing {
+			case Up:
+				e.rend.DrawLine(DebugLine{x0, y1 - 2, x1, y1 - 2, clr})
+			case Down:
+				e.rend.DrawLine(DebugLine{x0, y0 + 2, x1, y0 + 2, clr})
+			case Left:
+				e.rend.DrawLine(DebugLine{x1 - 2, y0, x1 - 2, y1, clr})
+			case Right:
+				e.rend.DrawLine(DebugLine{x0 + 2, y0, x0 + 2, y1, clr})
+		}
+	}
+}
+
+// setMapProperties changes the properties of the active map
+func (e *Editor) setMapProperties(props MapProperties) {
+	if props == e.activeTileMap.Properties {
+		return
+	}
+
+	delta := &PropertiesDelta{
+		e.activeTileMap.Properties,
+		props,
+		e.activeTileMapIndex,
+	}
+	e.activeTileMap.Properties = props
+	UndoStack = append(UndoStack, delta)
+	RedoStack = RedoStack[:0]
+}
+
+// pickFile asks for a file within dir, returning its path relative to dir or
+// the empty string if none was picked
+func (e *Editor) pickFile(title, dir string) string {
+	file, err := dialog.File().Title(title).Filter("All Files", "*").SetStartDir(dir).Load()
+	os.Chdir(WorkingDir)
+	if err != nil {
+		return ""
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.Base(file)
+	}
+	rel, err := filepath.Rel(abs, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
 func listPngs(dir string) []string {
 	return listWithExtension(dir, ".png")
 }
@@ -1,5 +1,9 @@
 package pok
 
+import(
+	"github.com/atemmel/pok/pkg/debug"
+)
+
 type Delta interface {
 	Undo(ed *Editor)
 	Redo(ed *Editor)
@@ -19,6 +23,10 @@ var CurrentRemoveLinkDelta *RemoveLinkDelta = &RemoveLinkDelta{}
 var CurrentAutotileDelta *AutotileDelta = &AutotileDelta{}
 var CurrentNpcDelta *NpcDelta = &NpcDelta{}
 var CurrentRemoveNpcDelta *RemoveNpcDelta = &RemoveNpcDelta{}
+var CurrentTriggerDelta *TriggerDelta = &TriggerDelta{}
+var CurrentRemoveTriggerDelta *RemoveTriggerDelta = &RemoveTriggerDelta{}
+var CurrentInteractableDelta *InteractableDelta = &InteractableDelta{}
+var CurrentRemoveInteractableDelta *RemoveInteractableDelta = &RemoveInteractableDelta{}
 
 var CurrentResizeDelta *ResizeDelta = &ResizeDelta{}
 
@@ -367,7 +375,7 @@ func (dn *NpcDelta) Undo(ed *Editor) {
 func (dn *NpcDelta) Redo(ed *Editor) {
 	tm := ed.tileMaps[dn.tileMapIndex]
 	dn.npcIndex = len(tm.npcs)
-	tm.PlaceNpc(dn.npcInfo)
+	debug.Assert(tm.PlaceNpc(dn.npcInfo))
 }
 
 type RemoveNpcDelta struct {
@@ -381,3 +389,75 @@ func (drn *RemoveNpcDelta) Undo(ed *Editor) {
 func (drn *RemoveNpcDelta) Redo(ed *Editor) {
 	drn.npcDelta.Undo(ed)
 }
+
+type TriggerDelta struct {
+	trigger *Trigger
+	triggerIndex int
+	tileMapIndex int
+}
+
+func (dt *TriggerDelta) Undo(ed *Editor) {
+	tm := ed.tileMaps[dt.tileMapIndex]
+	tm.RemoveTrigger(dt.triggerIndex)
+}
+
+func (dt *TriggerDelta) Redo(ed *Editor) {
+	tm := ed.tileMaps[dt.tileMapIndex]
+	debug.Assert(tm.PlaceTrigger(dt.trigger))
+	dt.triggerIndex = len(tm.Triggers) - 1
+}
+
+type RemoveTriggerDelta struct {
+	triggerDelta *TriggerDelta
+}
+
+func (drt *RemoveTriggerDelta) Undo(ed *Editor) {
+	drt.triggerDelta.Redo(ed)
+}
+
+func (drt *RemoveTriggerDelta) Redo(ed *Editor) {
+	drt.triggerDelta.Undo(ed)
+}
+
+type InteractableDelta struct {
+	interactable *Interactable
+	interactableIndex int
+	tileMapIndex int
+}
+
+func (di *InteractableDelta) Undo(ed *Editor) {
+	tm := ed.tileMaps[di.tileMapIndex]
+	tm.RemoveInteractable(di.interactableIndex)
+}
+
+func (di *InteractableDelta) Redo(ed *Editor) {
+	tm := ed.tileMaps[di.tileMapIndex]
+	debug.Assert(tm.PlaceInteractable(di.interactable))
+	di.interactableIndex = len(tm.Interactables) - 1
+}
+
+type RemoveInteractableDelta struct {
+	interactableDelta *InteractableDelta
+}
+
+func (dri *RemoveInteractableDelta) Undo(ed *Editor) {
+	dri.interactableDelta.Redo(ed)
+}
+
+func (dri *RemoveInteractableDelta) Redo(ed *Editor) {
+	dri.interactableDelta.Undo(ed)
+}
+
+type PropertiesDelta struct {
+	oldProperties MapProperties
+	newProperties MapProperties
+	tileMapIndex int
+}
+
+func (dp *PropertiesDelta) Undo(ed *Editor) {
+	ed.tileMaps[dp.tileMapIndex].Properties = dp.oldProperties
+}
+
+func (dp *PropertiesDelta) Redo(ed *Editor) {
+	ed.tileMaps[dp.tileMapIndex].Properties = dp.newProperties
+}
@@ -0,0 +1,127 @@
+package pok
+
+import(
+	"errors"
+	"fmt"
+	"github.com/atemmel/pok/pkg/dialog"
+	"log"
+	"strconv"
+)
+
+// EffectHandler performs a named effect requested by a dialog tree. NArgs is
+// the number of arguments the effect expects.
+type EffectHandler struct {
+	NArgs int
+	Do func(g *Game, args []string) error
+}
+
+var effectHandlers = make(map[string]EffectHandler)
+
+// RegisterEffect makes an effect available to dialog trees, replacing any
+// earlier effect with the same name
+func RegisterEffect(name string, handler EffectHandler) {
+	effectHandlers[name] = handler
+}
+
+func init() {
+	RegisterEffect("surf", EffectHandler{
+		NArgs: 0,
+		Do: func(g *Game, args []string) error {
+			beginSurf(g)
+			return nil
+		},
+	})
+
+	RegisterEffect("warp", EffectHandler{
+		NArgs: 2,
+		Do: warpEffect,
+	})
+
+	RegisterEffect("heal_party", EffectHandler{
+		NArgs: 0,
+		Do: healPartyEffect,
+	})
+
+	RegisterEffect("play_sound", EffectHandler{
+		NArgs: 1,
+		Do: playSoundEffect,
+	})
+
+	for name, fe := range dialog.FlagEffects {
+		do := fe.Do
+		RegisterEffect(name, EffectHandler{
+			NArgs: fe.NArgs,
+			Do: func(g *Game, args []string) error {
+				return do(g.Flags, args)
+			},
+		})
+	}
+}
+
+func lookupEffect(effect string) (EffectHandler, []string, error) {
+	name, args := dialog.ParseEffect(effect)
+	handler, ok := effectHandlers[name]
+	if !ok {
+		return handler, nil, errors.New("Unknown effect \"" + name + "\"")
+	}
+
+	if len(args) != handler.NArgs {
+		return handler, nil, fmt.Errorf("Effect \"%s\" expects %d arguments, got %d", name, handler.NArgs, len(args))
+	}
+
+	return handler, args, nil
+}
+
+// ValidateEffects checks that every effect in tree is registered and given
+// the right amount of arguments
+func ValidateEffects(tree *dialog.DialogTree) error {
+	for i, node := range *tree {
+		effect, ok := node.(*dialog.EffectDialogNode)
+		if !ok {
+			continue
+		}
+
+		if _, _, err := lookupEffect(effect.Effect); err != nil {
+			return fmt.Errorf("Node %d: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+func (g *Game) RunEffect(effect string) error {
+	handler, args, err := lookupEffect(effect)
+	if err != nil {
+		return err
+	}
+	return handler.Do(g, args)
+}
+
+func warpEffect(g *Game, args []string) error {
+	entryId, err := strconv.Atoi(args[1])
+	if err != nil {
+		return err
+	}
+
+	g.BeginTransition(args[0], entryId)
+	return nil
+}
+
+// There is no party to heal yet, so healing only notes that it happened
+func healPartyEffect(g *Game, args []string) error {
+	log.Println("heal_party: the player has no party to heal")
+	return nil
+}
+
+func playSoundEffect(g *Game, args []string) error {
+	switch args[0] {
+		case "door":
+			g.Audio.PlayDoor()
+		case "thud":
+			g.Audio.PlayThud()
+		case "jump":
+			g.Audio.PlayPlayerJump()
+		default:
+			return errors.New("Unknown sound \"" + args[0] + "\"")
+	}
+	return nil
+}
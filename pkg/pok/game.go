@@ -3,8 +3,10 @@ package pok
 import (
 	"github.com/atemmel/pok/pkg/constants"
 	"github.com/atemmel/pok/pkg/debug"
+	"github.com/atemmel/pok/pkg/dialog"
 	"github.com/atemmel/pok/pkg/jobs"
 	"github.com/atemmel/pok/pkg/textures"
+	"github.com/atemmel/pok/pkg/world"
 	"github.com/hajimehoshi/ebiten/v2"
 	"image"
 	"math"
@@ -18,11 +20,18 @@ type Game struct {
 	Rend Renderer
 	Audio Audio
 	Dialog DialogBox
+	Flags dialog.Flags
+	Weather WeatherEffect
+	MapName MapNameBanner
+	// Wild encounters of the current map, although none happen yet
+	EncounterTable string
+	World *world.World
 }
 
 func CreateGame() *Game {
 	g := &Game{}
 	g.As = &g.Ows
+	g.Flags = make(dialog.Flags)
 	var err error
 	playerImg, err = textures.LoadWithError(constants.CharacterImagesDir + "trchar000.png")
 	debug.Assert(err)
@@ -38,15 +47,18 @@ func CreateGame() *Game {
 	debug.Assert(err)
 	playerUsingHMImg, err = textures.LoadWithError(constants.ImagesDir + "hm_anim.png")
 	debug.Assert(err)
+	surfDialog, err = loadDialogTree(surfDialogPath)
+	debug.Assert(err)
 
 	activePlayerImg = playerImg
 	g.Dialog = NewDialogBox()
+	g.Dialog.Vars = g.ResolveVar
 	drawUi = false
 
-	// animate water
+	// animate tiles
 	jobs.Add(jobs.Job{
-		Do: WaterAnim,
-		When: 11,
+		Do: AnimateTiles,
+		When: 1,
 	})
 
 	// animate water splashes
@@ -68,7 +80,7 @@ func CreateGame() *Game {
 
 	jobs.Add(jobs.Job{
 		Do: func() {
-			red, green, blue := GetActiveEffect()
+			red, green, blue := g.Lighting()
 			g.Rend.SetEffect(red, green, blue)
 		},
 		When: 60,
@@ -80,23 +92,31 @@ func CreateGame() *Game {
 var sharpedoBiteStep int = 0
 const nSharpedoBiteSteps = 3
 
+// The player bobs on the water in steps while surfing
+const(
+	nSurfBobSteps = 11
+	surfBobStepTicks = 11
+)
+
 func (g *Game) TileIsOccupied(x int, y int, z int) bool {
-	if x < 0 || x >= g.Ows.tileMap.Width || y < 0 ||  y >= g.Ows.tileMap.Height {
+	// The tile may lie past the edge of the current map, in a neighbouring one
+	tm, lx, ly := g.Ows.mapAt(x, y)
+	if tm == nil {
 		return true
 	}
 
-	index := y * g.Ows.tileMap.Width + x
+	index := tm.Index(lx, ly)
 
 	// Out of bounds check
-	if z < 0 || z >= len(g.Ows.tileMap.Tiles) {
+	if z < 0 || z >= len(tm.Tiles) {
 		return true
 	}
 
-	if index >= len(g.Ows.tileMap.Tiles[z]) || index < 0 {
+	if index >= len(tm.Tiles[z]) || index < 0 {
 		return true
 	}
 
-	if g.Ows.tileMap.Collision[z][index] {
+	if tm.Collision[z][index] {
 		return true
 	}
 
@@ -106,9 +126,9 @@ func (g *Game) TileIsOccupied(x int, y int, z int) bool {
 		}
 	}
 
-	for i := range g.Ows.tileMap.npcs {
-		c := &g.Ows.tileMap.npcs[i].Char
-		if c.X == x && c.Y == y && c.Z == z {
+	for i := range tm.npcs {
+		c := &tm.npcs[i].Char
+		if c.X == lx && c.Y == ly && c.Z == z {
 			return true
 		}
 	}
@@ -136,9 +156,21 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	g.As.Draw(g, screen)
 }
 
-func (g *Game) Load(str string, entrypoint int) {
-	err := g.Ows.tileMap.OpenFile(str)
-	debug.Assert(err)
+// Load opens the map at str and places the player at the entry with the
+// given id
+func (g *Game) Load(str string, entrypoint int) error {
+	tm := &TileMap{}
+	if err := tm.OpenFile(str); err != nil {
+		return err
+	}
+	g.Enter(tm, str, entrypoint)
+	return nil
+}
+
+// Enter makes tm, opened from str, the current map and places the player at
+// the entry with the given id
+func (g *Game) Enter(tm *TileMap, str string, entrypoint int) {
+	g.Ows.tileMap = *tm
 	currentLayer = 0
 	selectedTile = 0
 	g.Player.Location = str
@@ -152,13 +184,55 @@ func (g *Game) Load(str string, entrypoint int) {
 	}
 	g.Player.Char.Gx = float64(g.Player.Char.X * constants.TileSize)
 	g.Player.Char.Gy = float64(g.Player.Char.Y * constants.TileSize)
+	g.Player.StandStill()
+	g.loadNeighbours(str, g.Ows.neighbours)
 	g.Rend = NewRenderer(
 		constants.DisplaySizeX,
 		constants.DisplaySizeY,
 		2,
 	)
 
-	g.Rend.SetEffect(GetActiveEffect())
+	g.ApplyMapProperties()
+}
+
+// Flags which, when set, rename the player and the rival
+const(
+	PlayerNameFlag = "player_name"
+	RivalNameFlag = "rival_name"
+)
+
+const(
+	defaultPlayerName = "BRENDAN"
+	defaultRivalName = "MAY"
+)
+
+// ResolveVar looks up the text placeholders in dialog markup stand for
+func (g *Game) ResolveVar(name string) string {
+	switch name {
+		case dialog.VarPlayer:
+			if player := g.Flags.Get(PlayerNameFlag); player != "" {
+				return player
+			}
+			return defaultPlayerName
+		case dialog.VarRival:
+			if rival := g.Flags.Get(RivalNameFlag); rival != "" {
+				return rival
+			}
+			return defaultRivalName
+	}
+	if flag, ok := dialog.FlagName(name); ok {
+		return g.Flags.Get(flag)
+	}
+	return ""
+}
+
+// BeginTransition fades out of the current map and into the entry with the
+// given id on target, a path relative to the tile map directory. The target
+// is opened in the background while the screen fades
+func (g *Game) BeginTransition(target string, entryId int) {
+	img := ebiten.NewImage(constants.DisplaySizeX, constants.DisplaySizeY)
+	g.As.Draw(g, img)
+	g.As = NewTransitionState(img, LoadMapAsync(constants.TileMapDir + target), entryId)
 }
 
 func (g *Game) Save() {
@@ -188,7 +262,8 @@ func (g *Game) DrawPlayer(player *Player) {
 
 	waterBobOffsetY := 0.0
 	if player.Char.isSurfing {
-		scale := float64(waterFrameStep) / float64(nWaterFrames)
+		step := animationTicks / surfBobStepTicks % nSurfBobSteps
+		scale := float64(step) / float64(nSurfBobSteps)
 		waterBobOffsetY = math.Sin(scale * math.Pi) * 4.0
 	}
 
@@ -289,5 +364,7 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 }
 
 func (g *Game) PlayAudio() {
-	g.Audio.audioPlayer.Play()
+	if g.Audio.audioPlayer != nil {
+		g.Audio.audioPlayer.Play()
+	}
 }
@@ -0,0 +1,55 @@
+package pok
+
+import(
+	"errors"
+	"fmt"
+	"github.com/atemmel/pok/pkg/dialog"
+)
+
+// Interactable is a single tile the player may face and use, such as a sign,
+// a PC, an item ball or a bookshelf. It either shows a dialog tree or
+// performs an effect.
+type Interactable struct {
+	X, Y, Z int
+	// The way the player must face to use it, or Static for any way
+	Facing Direction
+	DialogPath string
+	Effect string
+}
+
+// UsableFrom reports whether a player facing dir may use the interactable
+func (in *Interactable) UsableFrom(dir Direction) bool {
+	return in.Facing == Static || in.Facing == dir
+}
+
+// Validate checks that the interactable is well formed, without looking at
+// its dialog tree
+func (in *Interactable) Validate() error {
+	if in.Facing < Static || in.Facing > Up {
+		return fmt.Errorf("Unknown direction %d", in.Facing)
+	}
+	if in.DialogPath == "" && in.Effect == "" {
+		return errors.New("Interactable has neither a dialog nor an effect")
+	}
+	if in.DialogPath != "" && in.Effect != "" {
+		return errors.New("Interactable has both a dialog and an effect")
+	}
+	if in.Effect != "" {
+		if _, _, err := lookupEffect(in.Effect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadInteractableDialog validates in and reads its dialog tree, if it has
+// one
+func loadInteractableDialog(in *Interactable) (*dialog.DialogTree, error) {
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	if in.DialogPath == "" {
+		return nil, nil
+	}
+	return loadDialogTree(in.DialogPath)
+}
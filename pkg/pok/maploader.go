@@ -0,0 +1,39 @@
+package pok
+
+// MapLoad opens a map in the background, so that reading it does not hold up
+// the frame it was asked for in
+type MapLoad struct {
+	Path string
+	done chan struct{}
+	tileMap *TileMap
+	err error
+}
+
+func LoadMapAsync(path string) *MapLoad {
+	l := &MapLoad{path, make(chan struct{}), nil, nil}
+	go func() {
+		tm := &TileMap{}
+		l.err = tm.OpenFile(path)
+		if l.err == nil {
+			l.tileMap = tm
+		}
+		close(l.done)
+	}()
+	return l
+}
+
+// Done reports whether the map has been opened, without waiting for it
+func (l *MapLoad) Done() bool {
+	select {
+		case <-l.done:
+			return true
+		default:
+			return false
+	}
+}
+
+// Wait blocks until the map has been opened
+func (l *MapLoad) Wait() (*TileMap, error) {
+	<-l.done
+	return l.tileMap, l.err
+}
@@ -0,0 +1,110 @@
+package pok
+
+import(
+	"github.com/atemmel/pok/pkg/constants"
+	"github.com/atemmel/pok/pkg/debug"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/fonts"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"log"
+)
+
+// MapProperties describe a map as a whole rather than any part of it
+type MapProperties struct {
+	// Shown when the player enters the map
+	Name string
+	// Track in the audio directory, or the default track if empty
+	Music string
+	Weather Weather
+	// Indoor maps are not tinted by the time of day
+	Indoor bool
+	// Table of wild encounters on the map, if any
+	Encounters string
+}
+
+// How long the name of a map is shown after entering it
+const mapNameTicks = 150
+
+var mapNameFont font.Face
+
+// MapNameBanner shows the name of the map the player just entered
+type MapNameBanner struct {
+	name string
+	img *ebiten.Image
+	ticks int
+}
+
+// Show displays name, unless it is already being shown or the player came
+// from a map of the same name
+func (b *MapNameBanner) Show(name string) {
+	if name == b.name {
+		return
+	}
+
+	b.name = name
+	b.ticks = 0
+	if name == "" {
+		b.img = nil
+		return
+	}
+
+	if mapNameFont == nil {
+		var err error
+		mapNameFont, err = fonts.LoadFont(constants.DialogFontPath, dialog.FontSize)
+		debug.Assert(err)
+	}
+
+	r := text.BoundString(mapNameFont, name)
+	w := r.Dx() + paddingX * 4
+	h := r.Dy() + paddingY * 4
+	b.img = ebiten.NewImageFromImage(buildBox(w, h))
+	text.Draw(b.img, name, mapNameFont, paddingX * 2 + 1, paddingY * 2 - r.Min.Y + 1, fgShadow)
+	text.Draw(b.img, name, mapNameFont, paddingX * 2, paddingY * 2 - r.Min.Y, fg)
+	b.ticks = mapNameTicks
+}
+
+func (b *MapNameBanner) Update() {
+	if b.ticks > 0 {
+		b.ticks--
+	}
+}
+
+func (b *MapNameBanner) Draw(screen *ebiten.Image) {
+	if b.ticks <= 0 || b.img == nil {
+		return
+	}
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Translate(paddingX, paddingY)
+	screen.DrawImage(b.img, opt)
+}
+
+// ApplyMapProperties sets the music, weather, lighting and encounters of the
+// game to those of the current map
+func (g *Game) ApplyMapProperties() {
+	props := &g.Ows.tileMap.Properties
+	if err := g.Audio.PlayMusic(props.Music); err != nil {
+		// A missing track should not end the game, the default one is
+		// played instead
+		log.Println("Could not play", props.Music + ":", err)
+		if err = g.Audio.PlayMusic(defaultTrack); err != nil {
+			log.Println("Could not play", defaultTrack + ":", err)
+		}
+	}
+	g.Weather.Set(props.Weather)
+	g.MapName.Show(props.Name)
+	g.EncounterTable = props.Encounters
+	g.Rend.SetEffect(g.Lighting())
+}
+
+// Lighting returns the tint of the overworld, which follows the time of day
+// outdoors and is darkened by the weather
+func (g *Game) Lighting() (float64, float64, float64) {
+	r, gr, b := 1.0, 1.0, 1.0
+	if !g.Ows.tileMap.Properties.Indoor {
+		r, gr, b = GetActiveEffect()
+	}
+	wr, wg, wb := g.Weather.Tint()
+	return r * wr, gr * wg, b * wb
+}
@@ -13,6 +13,7 @@ import(
 type Npc struct {
 	Char Character
 	Dialog *dialog.DialogTree
+	DialogPath string
 	NpcTextureIndex int
 	MovementInfo NpcMovementInfo
 	TalkedTo bool
@@ -53,9 +54,24 @@ const(
 	NpcOffsetY = -14
 )
 
-func BuildNpcFromNpcInfo(t *TileMap, info *NpcInfo) Npc {
-	tree, err := dialog.ReadDialogTreeFromFile(constants.DialogDir + info.DialogPath)
-	debug.Assert(err)
+// loadDialogTree reads the tree at path within the dialog directory, making
+// sure the game is able to show it
+func loadDialogTree(path string) (*dialog.DialogTree, error) {
+	tree, err := dialog.ReadDialogTreeFromFile(constants.DialogDir + path)
+	if err == nil {
+		err = ValidateEffects(tree)
+	}
+	if err == nil {
+		err = dialog.ValidateMarkup(tree)
+	}
+	return tree, err
+}
+
+func BuildNpcFromNpcInfo(t *TileMap, info *NpcInfo) (Npc, error) {
+	tree, err := loadDialogTree(info.DialogPath)
+	if err != nil {
+		return Npc{}, err
+	}
 
 	if info.MovementInfo.Strategy == Zone {
 		info.MovementInfo.zoneFramesUntilNextStep = SelectFramesUntilNextStep()
@@ -64,6 +80,7 @@ func BuildNpcFromNpcInfo(t *TileMap, info *NpcInfo) Npc {
 	npc := Npc{
 		Character{},
 		tree,
+		info.DialogPath,
 		-1,
 		info.MovementInfo,
 		false,
@@ -75,9 +92,8 @@ func BuildNpcFromNpcInfo(t *TileMap, info *NpcInfo) Npc {
 	npc.Char.X = info.X
 	npc.Char.Y = info.Y
 
-	_, npc.NpcTextureIndex = textures.Load(constants.CharacterImagesDir + info.Texture)
-
-	return npc
+	_, npc.NpcTextureIndex, err = textures.TryLoad(constants.CharacterImagesDir + info.Texture)
+	return npc, err
 }
 
 func (npc* Npc) Update(g *Game) {
@@ -9,6 +9,7 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"log"
 )
 
 var playerImg *ebiten.Image
@@ -21,6 +22,10 @@ var beachSplashImg *ebiten.Image
 
 var activePlayerImg *ebiten.Image
 
+var surfDialog *dialog.DialogTree
+
+const surfDialogPath = "surf.json"
+
 var selectedHm int = None
 
 func aboutToUseHM() bool {
@@ -54,6 +59,8 @@ type GameState interface {
 type OverworldState struct {
 	tileMap TileMap
 	collector dialog.DialogTreeCollector
+	// Maps placed next to the current one in the world
+	neighbours []Neighbour
 }
 
 func gamepadUp() bool {
@@ -93,7 +100,19 @@ func holdingSprint() bool {
 }
 
 func pressedInteract() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyZ) || inpututil.IsKeyJustPressed(ebiten.KeyE)
+	return inpututil.IsKeyJustPressed(ebiten.KeyZ) || inpututil.IsKeyJustPressed(ebiten.KeyE) || inpututil.IsGamepadButtonJustPressed(0, ebiten.GamepadButton0)
+}
+
+func pressedCancel() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeyX) || inpututil.IsKeyJustPressed(ebiten.KeyBackspace) || inpututil.IsGamepadButtonJustPressed(0, ebiten.GamepadButton1)
+}
+
+func pressedMenuUp() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyK) || inpututil.IsKeyJustPressed(ebiten.KeyW) || inpututil.IsGamepadButtonJustPressed(0, ebiten.GamepadButton11)
+}
+
+func pressedMenuDown() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyJ) || inpututil.IsKeyJustPressed(ebiten.KeyS) || inpututil.IsGamepadButtonJustPressed(0, ebiten.GamepadButton13)
 }
 
 func pressedItem() bool {
@@ -123,28 +142,88 @@ func (o *OverworldState) tryInteract(g *Game) {
 		npc := &(o.tileMap.npcs[i].Char)
 		if npc.X == x && npc.Y == y {
 			o.talkWith(g, i)
-			break
+			return
+		}
+	}
+
+	// check signs, bookshelves and the like
+	if i := o.tileMap.InteractableAt(x, y, g.Player.Char.Z); i != -1 {
+		in := &o.tileMap.Interactables[i]
+		if in.UsableFrom(g.Player.Char.dir) {
+			o.perform(g, o.tileMap.interactableDialogs[i], in.DialogPath, in.Effect)
+			return
+		}
+	}
+
+	// check interact triggers
+	for i := range o.tileMap.Triggers {
+		tr := &o.tileMap.Triggers[i]
+		if tr.Kind == InteractTrigger && tr.Contains(x, y, g.Player.Char.Z) && o.fireTrigger(g, i) {
+			return
 		}
 	}
 
 	// check water
 	if g.Player.Char.CoordinateContainsWater(x, y, g) {
 		if !g.Player.Char.isSurfing {
-			o.collector = dialog.MakeDialogTreeCollector(&dialog.DialogTree{
-				&dialog.DialogNode{
-					Dialog: "Sharpedo used Surf!",
-					Next: dialog.Link(1),
-				},
-				&dialog.EffectDialogNode{
-					Effect: "surf",
-					Next: nil,
-				},
-			})
-
-			result := o.collector.Peek()
-			g.Dialog.SetString(result.Dialog)
-			g.Dialog.Hidden = false
+			o.collector = dialog.MakeDialogTreeCollector(surfDialog, g.Flags)
+			o.collector.SetPath(surfDialogPath)
+			o.showDialog(g)
+		}
+	}
+}
+
+// checkStepTriggers fires the first trigger set off by the player finishing a
+// step which began at the given tile
+func (o *OverworldState) checkStepTriggers(g *Game, prevX, prevY, prevZ int) {
+	x, y, z := g.Player.Char.X, g.Player.Char.Y, g.Player.Char.Z
+	for i := range o.tileMap.Triggers {
+		tr := &o.tileMap.Triggers[i]
+		if !tr.Contains(x, y, z) {
+			continue
+		}
+		if tr.Kind == InteractTrigger || (tr.Kind == AreaTrigger && tr.Contains(prevX, prevY, prevZ)) {
+			continue
 		}
+		if o.fireTrigger(g, i) {
+			return
+		}
+	}
+}
+
+// fireTrigger sets off the trigger at index unless its conditions keep it
+// from firing, reporting whether it fired
+func (o *OverworldState) fireTrigger(g *Game, index int) bool {
+	tr := &o.tileMap.Triggers[index]
+	if !tr.Ready(g.Flags, g.Player.Location) {
+		return false
+	}
+
+	if tr.Once {
+		g.Flags.Set(TriggerFlag(g.Player.Location, tr.Id), "true")
+	}
+
+	o.perform(g, o.tileMap.triggerDialogs[index], tr.DialogPath, tr.Effect)
+	return true
+}
+
+// perform runs effect if there is one, and otherwise shows tree, which was
+// read from path
+func (o *OverworldState) perform(g *Game, tree *dialog.DialogTree, path, effect string) {
+	if effect != "" {
+		runEffect(g, effect)
+		return
+	}
+
+	o.collector = dialog.MakeDialogTreeCollector(tree, g.Flags)
+	o.collector.SetPath(path)
+	o.showDialog(g)
+}
+
+// runEffect performs effect, logging rather than ending the game if it fails
+func runEffect(g *Game, effect string) {
+	if err := g.RunEffect(effect); err != nil {
+		log.Println("Could not perform effect", effect + ":", err)
 	}
 }
 
@@ -166,14 +245,14 @@ func (o *OverworldState) talkWith(g *Game, npcIndex int) {
 	char.SetDirection(dir)
 	o.tileMap.npcs[npcIndex].TalkedTo = true
 	tree := o.tileMap.npcs[npcIndex].Dialog
-	o.collector = dialog.MakeDialogTreeCollector(tree)
-	result := o.collector.CollectOnce()
-	if result != nil {
-		g.Dialog.SetString(result.Dialog)
+	o.collector = dialog.MakeDialogTreeCollector(tree, g.Flags)
+	o.collector.SetPath(o.tileMap.npcs[npcIndex].DialogPath)
+	if o.collector.Peek() != nil {
+		o.showDialog(g)
 	} else {
-		g.Dialog.SetString("Result was nil and shouldn't be >:(")
+		g.Dialog.SetString("$ui.no_dialog")
+		g.Dialog.Hidden = false
 	}
-	g.Dialog.Hidden = false
 }
 
 func (o *OverworldState) GetInputs(g *Game) error {
@@ -226,31 +305,110 @@ func (o *OverworldState) CheckMovementInputs(g *Game) {
 
 func (o *OverworldState) CheckDialogInputs(g *Game) {
 	g.Player.Char.TryStep(Static, g)
-	if g.Dialog.IsDone() {
-
-		COLLECT_AGAIN:
+	if !g.Dialog.IsDone() {
+		return
+	}
 
-		result := o.collector.Peek()
-		if result == nil {
-			g.Dialog.Hidden = true
-			return
+	if g.Dialog.HasNextPage() {
+		if pressedInteract() {
+			g.Dialog.NextPage()
 		}
+		return
+	}
 
-		switch result.NodeId {
-			case dialog.DialogNodeId:
-				if pressedInteract() {
-					_ = o.collector.CollectOnce()
-					goto COLLECT_AGAIN
-				}
-				break
-			case dialog.EffectDialogNodeId:
-				if result.Opt == "surf" {
-					beginSurf(g)
-				}
-				_ = o.collector.CollectOnce();
-				goto COLLECT_AGAIN
-		}
+	result := o.collector.Peek()
+	if result == nil {
+		g.Dialog.Hidden = true
+		return
+	}
+
+	switch result.NodeId {
+		case dialog.DialogNodeId:
+			if pressedInteract() {
+				_ = o.collector.CollectOnce()
+				o.showDialog(g)
+			}
+		case dialog.BinaryDialogNodeId, dialog.ChoiceDialogNodeId:
+			o.checkChoiceInputs(g)
+	}
+}
+
+func (o *OverworldState) checkChoiceInputs(g *Game) {
+	if pressedMenuUp() {
+		g.Dialog.MoveChoice(-1)
+		o.collector.Highlight(g.Dialog.SelectedChoice())
+	} else if pressedMenuDown() {
+		g.Dialog.MoveChoice(1)
+		o.collector.Highlight(g.Dialog.SelectedChoice())
+	}
+
+	if pressedInteract() {
+		o.collector.Choose(g.Dialog.SelectedChoice())
+	} else if pressedCancel() {
+		o.collector.Choose(g.Dialog.LastChoice())
+	} else {
+		return
+	}
+
+	g.Dialog.ClearChoices()
+	o.showDialog(g)
+}
+
+// DialogCursor returns where the ongoing conversation stands, or nil if the
+// player is not in one
+func (o *OverworldState) DialogCursor() *dialog.DialogCursor {
+	if o.collector.Peek() == nil {
+		return nil
+	}
+	cursor := o.collector.Cursor()
+	return &cursor
+}
+
+// ResumeDialog picks a conversation up from where cursor stands
+func (o *OverworldState) ResumeDialog(g *Game, cursor dialog.DialogCursor) error {
+	tree, err := loadDialogTree(cursor.Path)
+	if err != nil {
+		return err
+	}
+
+	o.collector = dialog.MakeDialogTreeCollector(tree, g.Flags)
+	if err = o.collector.Restore(cursor); err != nil {
+		return err
+	}
+
+	o.showDialog(g)
+	if g.Dialog.HasChoices() {
+		g.Dialog.SelectChoice(cursor.Choice)
+		o.collector.Highlight(g.Dialog.SelectedChoice())
+	}
+	return nil
+}
+
+// showDialog displays the node the collector currently rests on, performing
+// any effects found along the way
+func (o *OverworldState) showDialog(g *Game) {
+
+	COLLECT_AGAIN:
+
+	result := o.collector.Peek()
+	if result == nil {
+		g.Dialog.Hidden = true
+		return
+	}
+
+	switch result.NodeId {
+		case dialog.EffectDialogNodeId:
+			runEffect(g, result.Opt)
+			_ = o.collector.CollectOnce()
+			goto COLLECT_AGAIN
+		case dialog.BinaryDialogNodeId:
+			g.Dialog.SetChoices(dialog.YesNoChoices)
+		case dialog.ChoiceDialogNodeId:
+			g.Dialog.SetChoices(result.Choices)
 	}
+
+	g.Dialog.SetString(result.Dialog)
+	g.Dialog.Hidden = false
 }
 
 func beginSurf(g *Game) {
@@ -280,6 +438,7 @@ func beginSurf(g *Game) {
 }
 
 func (o *OverworldState) Update(g *Game) error {
+	o.updateNeighbours()
 	g.Player.Update(g)
 	jobs.TickAllOneFrame()
 	o.tileMap.UpdateNpcs(g)
@@ -289,11 +448,14 @@ func (o *OverworldState) Update(g *Game) error {
 	}
 
 	g.Dialog.Update()
+	g.Weather.Update()
+	g.MapName.Update()
 
 	return nil
 }
 
 func (o *OverworldState) Draw(g *Game, screen *ebiten.Image) {
+	o.drawNeighbours(&g.Rend)
 	o.tileMap.Draw(&g.Rend)
 	g.DrawPlayer(&g.Player)
 
@@ -309,6 +471,8 @@ func (o *OverworldState) Draw(g *Game, screen *ebiten.Image) {
 
 	g.CenterRendererOnPlayer()
 	g.Rend.Display(screen)
+	g.Weather.Draw(screen)
+	g.MapName.Draw(screen)
 
 	if drawUi {
 		ebitenutil.DebugPrint(screen, fmt.Sprintf(
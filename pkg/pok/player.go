@@ -1,15 +1,13 @@
 package pok
 
-import (
-	"github.com/atemmel/pok/pkg/constants"
-	"github.com/hajimehoshi/ebiten/v2"
-)
-
 type Player struct {
 	Id int
 	Char Character
 	Connected bool
 	Location string
+
+	// Where the player stood before the last step, for area triggers
+	prevX, prevY, prevZ int
 }
 
 const hmAnimFramesPerStep = 8
@@ -35,13 +33,19 @@ func (player *Player) Update(g *Game) {
 		}
 
 		player.Char.isWalking = false
-		if i := g.Ows.tileMap.HasExitAt(player.Char.X, player.Char.Y, player.Char.Z); i > -1 {
-			if g.Ows.tileMap.Exits[i].Target != "" {
-				img := ebiten.NewImage(constants.DisplaySizeX, constants.DisplaySizeY)
-				g.As.Draw(g, img)
-				g.As = NewTransitionState(img, constants.TileMapDir + g.Ows.tileMap.Exits[i].Target, g.Ows.tileMap.Exits[i].Id)
-				g.Audio.PlayDoor()
-			}
+		g.crossBorder()
+		if i := g.Ows.tileMap.HasExitAt(player.Char.X, player.Char.Y, player.Char.Z); i > -1 && g.Ows.tileMap.Exits[i].Target != "" {
+			g.BeginTransition(g.Ows.tileMap.Exits[i].Target, g.Ows.tileMap.Exits[i].Id)
+			g.Audio.PlayDoor()
+		} else {
+			g.Ows.checkStepTriggers(g, player.prevX, player.prevY, player.prevZ)
 		}
+		player.StandStill()
 	}
 }
+
+// StandStill forgets where the player came from, so that the tile they stand
+// on counts as already entered
+func (player *Player) StandStill() {
+	player.prevX, player.prevY, player.prevZ = player.Char.X, player.Char.Y, player.Char.Z
+}
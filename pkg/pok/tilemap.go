@@ -1,9 +1,12 @@
 package pok
 
 import(
-	"encoding/json"
+	"fmt"
 	"github.com/atemmel/pok/pkg/constants"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/mapfile"
 	"github.com/atemmel/pok/pkg/textures"
+	"github.com/atemmel/pok/pkg/tileset"
 	"github.com/hajimehoshi/ebiten/v2"
 	"io/ioutil"
 	"image"
@@ -12,22 +15,14 @@ import(
 
 var debugLoadingEnabled bool = true
 
-type Exit struct {
-	Target string
-	Id int
-	X int
-	Y int
-	Z int
-}
+type Exit = mapfile.Exit
 
-type Entry struct {
-	Id int
-	X int
-	Y int
-	Z int
-}
+type Entry = mapfile.Entry
 
 type TileMap struct {
+	FormatVersion int
+	// How layers are written to file, kept as the map was loaded
+	Encoding string
 	Tiles [][]int
 	Collision [][]bool
 	TextureIndicies [][]int
@@ -37,20 +32,25 @@ type TileMap struct {
 	Width int
 	Height int
 	NpcInfo []NpcInfo
+	Triggers []Trigger
+	Interactables []Interactable
+	Properties MapProperties
 
 	textureMapping []int
+	tilesets []*tileset.Tileset
 
 	npcs []Npc
+	// The dialog tree of each trigger, or nil for triggers with an effect
+	triggerDialogs []*dialog.DialogTree
+	// The dialog tree of each interactable, or nil for those with an effect
+	interactableDialogs []*dialog.DialogTree
 }
 
-var waterFrameStep int = 0
-const nWaterFrames = 11
+// Ticks passed since tiles started animating
+var animationTicks int = 0
 
-func WaterAnim() {
-	waterFrameStep++
-	if waterFrameStep >= nWaterFrames {
-		waterFrameStep = 0
-	}
+func AnimateTiles() {
+	animationTicks++
 }
 
 func (t *TileMap) HasExitAt(x, y, z int) int {
@@ -82,6 +82,7 @@ func (t *TileMap) HasTexture(index int) bool {
 
 func (t *TileMap) AppendTexture(index int, str string) {
 	t.textureMapping = append(t.textureMapping, index)
+	t.tilesets = append(t.tilesets, tileset.Get(str))
 	t.Textures = append(t.Textures, str)
 }
 
@@ -105,10 +106,24 @@ func (t *TileMap) UpdateNpcs(g *Game) {
 }
 
 func (t *TileMap) IsCoordCloseToWater(x, y, z int) bool {
-	index := t.Index(x, y)
-	texIndex := t.textureMapping[t.TextureIndicies[z][index]]
-	tileInTex := t.Tiles[z][index]
-	return textures.IsWater(texIndex) && tileInTex != 70
+	return t.HasTag(t.Index(x, y), z, tileset.Water)
+}
+
+// HasTag reports whether the tile at index of layer z is tagged with tag by
+// the tileset of its texture
+func (t *TileMap) HasTag(index, z int, tag string) bool {
+	if z < 0 || z >= len(t.Tiles) || z >= len(t.TextureIndicies) {
+		return false
+	}
+	if index < 0 || index >= len(t.Tiles[z]) || index >= len(t.TextureIndicies[z]) {
+		return false
+	}
+	tile := t.Tiles[z][index]
+	texture := t.TextureIndicies[z][index]
+	if tile < 0 || texture < 0 || texture >= len(t.tilesets) {
+		return false
+	}
+	return t.tilesets[texture].Has(tile, tag)
 }
 
 func (t *TileMap) drawNpcs(rend *Renderer, offsetX, offsetY float64) {
@@ -134,10 +149,7 @@ func (t *TileMap) DrawWithOffset(rend *Renderer, offsetX, offsetY float64) {
 			y := float64(iy) * constants.TileSize
 
 			index := t.textureMapping[t.TextureIndicies[j][i]]
-
-			if textures.IsWater(index) {
-				n += waterFrameStep * 6
-			}
+			n = t.tilesets[t.TextureIndicies[j][i]].Animate(n, animationTicks)
 
 			img := textures.Access(index)
 			nTilesX := img.Bounds().Dx() / constants.TileSize
@@ -186,24 +198,41 @@ func (t *TileMap) OpenFile(path string) error {
 			return err
 		}
 	}
-	err = json.Unmarshal(data, t)
-	if err != nil {
+	f := mapfile.File{}
+	if _, err = f.Decode(data); err != nil {
 		return err
 	}
+	t.fromFile(&f)
 
 	indicies := make([]int, len(t.Textures))
 
 	for i := range indicies {
-		_, index := textures.Load(constants.TileMapImagesDir + t.Textures[i])
+		_, index, err := textures.TryLoad(constants.TileMapImagesDir + t.Textures[i])
+		if err != nil {
+			return err
+		}
 		indicies[i] = index
 	}
 
 	t.textureMapping = indicies
+	t.tilesets = loadTilesets(t.Textures)
 
 	t.npcs = t.npcs[:0]
 	err = t.createNpcs()
+	if err != nil {
+		return err
+	}
 
-	return err
+	if t.Properties.Weather < 0 || t.Properties.Weather >= NWeathers {
+		return fmt.Errorf("Unknown weather %d", t.Properties.Weather)
+	}
+
+	err = t.loadTriggers()
+	if err != nil {
+		return err
+	}
+
+	return t.loadInteractables()
 }
 
 func (t *TileMap) Index(x, y int) int {
@@ -215,13 +244,147 @@ func (t *TileMap) Coords(i int) (int, int) {
 }
 
 func (t *TileMap) SaveToFile(path string) error {
-	data, err := json.Marshal(t)
+	t.FormatVersion = mapfile.FormatVersion
+	f := t.toFile()
+	data, err := f.Encode()
 	if err != nil {
 		return err
 	}
 	return ioutil.WriteFile(path, data, 0644)
 }
 
+// fromFile takes the contents of a map file, converting its objects into
+// those used by the game
+func (t *TileMap) fromFile(f *mapfile.File) {
+	t.FormatVersion = f.FormatVersion
+	t.Encoding = f.Encoding
+	t.Tiles = f.Tiles
+	t.Collision = f.Collision
+	t.TextureIndicies = f.TextureIndicies
+	t.Textures = f.Textures
+	t.Exits = f.Exits
+	t.Entries = f.Entries
+	t.Width = f.Width
+	t.Height = f.Height
+
+	t.NpcInfo = make([]NpcInfo, len(f.NpcInfo))
+	for i, ni := range f.NpcInfo {
+		t.NpcInfo[i] = NpcInfo{
+			ni.Texture,
+			ni.DialogPath,
+			ni.X, ni.Y, ni.Z,
+			NpcMovementInfo{
+				Strategy: NpcMovementStrategy(ni.MovementInfo.Strategy),
+				Commands: ni.MovementInfo.Commands,
+			},
+		}
+	}
+
+	t.Triggers = make([]Trigger, len(f.Triggers))
+	for i, tr := range f.Triggers {
+		t.Triggers[i] = Trigger{
+			tr.Id,
+			TriggerKind(tr.Kind),
+			tr.X, tr.Y, tr.Z,
+			tr.W, tr.H,
+			tr.DialogPath,
+			tr.Effect,
+			tr.Conditions,
+			tr.Once,
+		}
+	}
+
+	t.Interactables = make([]Interactable, len(f.Interactables))
+	for i, in := range f.Interactables {
+		t.Interactables[i] = Interactable{
+			in.X, in.Y, in.Z,
+			Direction(in.Facing),
+			in.DialogPath,
+			in.Effect,
+		}
+	}
+
+	t.Properties = MapProperties{
+		f.Properties.Name,
+		f.Properties.Music,
+		Weather(f.Properties.Weather),
+		f.Properties.Indoor,
+		f.Properties.Encounters,
+	}
+}
+
+// toFile returns the map as it is written to file
+func (t *TileMap) toFile() mapfile.File {
+	f := mapfile.File{
+		FormatVersion: t.FormatVersion,
+		Tiles: t.Tiles,
+		Collision: t.Collision,
+		TextureIndicies: t.TextureIndicies,
+		Textures: t.Textures,
+		Exits: t.Exits,
+		Entries: t.Entries,
+		Width: t.Width,
+		Height: t.Height,
+		NpcInfo: make([]mapfile.Npc, len(t.NpcInfo)),
+		Triggers: make([]mapfile.Trigger, len(t.Triggers)),
+		Interactables: make([]mapfile.Interactable, len(t.Interactables)),
+		Properties: mapfile.Properties{
+			Name: t.Properties.Name,
+			Music: t.Properties.Music,
+			Weather: int(t.Properties.Weather),
+			Indoor: t.Properties.Indoor,
+			Encounters: t.Properties.Encounters,
+		},
+	}
+	// Maps are written as JSON unless they were read packed
+	if t.Encoding != mapfile.EncodingJSON {
+		f.Encoding = t.Encoding
+	}
+
+	for i, ni := range t.NpcInfo {
+		f.NpcInfo[i] = mapfile.Npc{
+			Texture: ni.Texture,
+			DialogPath: ni.DialogPath,
+			X: ni.X,
+			Y: ni.Y,
+			Z: ni.Z,
+			MovementInfo: mapfile.Movement{
+				Strategy: int(ni.MovementInfo.Strategy),
+				Commands: ni.MovementInfo.Commands,
+			},
+		}
+	}
+
+	for i, tr := range t.Triggers {
+		f.Triggers[i] = mapfile.Trigger{
+			Id: tr.Id,
+			Kind: int(tr.Kind),
+			X: tr.X,
+			Y: tr.Y,
+			Z: tr.Z,
+			W: tr.W,
+			H: tr.H,
+			DialogPath: tr.DialogPath,
+			Effect: tr.Effect,
+			Conditions: tr.Conditions,
+			Once: tr.Once,
+		}
+	}
+
+	for i, in := range t.Interactables {
+		f.Interactables[i] = mapfile.Interactable{
+			X: in.X,
+			Y: in.Y,
+			Z: in.Z,
+			Facing: int(in.Facing),
+			DialogPath: in.DialogPath,
+			Effect: in.Effect,
+		}
+	}
+
+	return f
+}
+
 func (t *TileMap) NTilesX(textureIndex int) int {
 	img := textures.Access(textureIndex)
 	w, _ := img.Size()
@@ -494,6 +657,8 @@ func (t *TileMap) Resize(dx, dy, origin int) {
 	}
 
 	t.moveNpcs(ndx, ndy)
+	t.moveTriggers(ndx, ndy)
+	t.moveInteractables(ndx, ndy)
 }
 
 func (t *TileMap) moveNpcs(dx, dy int) {
@@ -523,6 +688,20 @@ func (t *TileMap) moveNpcs(dx, dy int) {
 	}
 }
 
+func (t *TileMap) moveTriggers(dx, dy int) {
+	for i := range t.Triggers {
+		t.Triggers[i].X += dx
+		t.Triggers[i].Y += dy
+	}
+}
+
+func (t *TileMap) moveInteractables(dx, dy int) {
+	for i := range t.Interactables {
+		t.Interactables[i].X += dx
+		t.Interactables[i].Y += dy
+	}
+}
+
 func (t *TileMap) PlaceEntry(entry Entry) {
 	t.Entries = append(t.Entries, entry)
 }
@@ -531,9 +710,14 @@ func (t *TileMap) PlaceExit(exit Exit) {
 	t.Exits = append(t.Exits, exit)
 }
 
-func (t *TileMap) PlaceNpc(ni *NpcInfo) {
+func (t *TileMap) PlaceNpc(ni *NpcInfo) error {
+	npc, err := BuildNpcFromNpcInfo(t, ni)
+	if err != nil {
+		return err
+	}
 	t.NpcInfo = append(t.NpcInfo, *ni)
-	t.npcs = append(t.npcs, BuildNpcFromNpcInfo(t, ni))
+	t.npcs = append(t.npcs, npc)
+	return nil
 }
 
 func (t *TileMap) RemoveNpc(index int) {
@@ -543,6 +727,71 @@ func (t *TileMap) RemoveNpc(index int) {
 	t.npcs = t.npcs[:len(t.npcs) - 1]
 }
 
+func (t *TileMap) PlaceTrigger(tr *Trigger) error {
+	tree, err := loadTriggerDialog(tr)
+	if err != nil {
+		return err
+	}
+	t.Triggers = append(t.Triggers, *tr)
+	t.triggerDialogs = append(t.triggerDialogs, tree)
+	return nil
+}
+
+func (t *TileMap) RemoveTrigger(index int) {
+	copy(t.Triggers[index:], t.Triggers[index + 1:])
+	copy(t.triggerDialogs[index:], t.triggerDialogs[index + 1:])
+	t.Triggers = t.Triggers[:len(t.Triggers) - 1]
+	t.triggerDialogs = t.triggerDialogs[:len(t.triggerDialogs) - 1]
+}
+
+// TriggerAt returns the index of the last placed trigger covering the tile,
+// or -1
+func (t *TileMap) TriggerAt(x, y, z int) int {
+	for i := len(t.Triggers) - 1; i >= 0; i-- {
+		if t.Triggers[i].Contains(x, y, z) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextTriggerId returns an id not used by any trigger of the map
+func (t *TileMap) NextTriggerId() int {
+	id := 0
+	for i := range t.Triggers {
+		if t.Triggers[i].Id >= id {
+			id = t.Triggers[i].Id + 1
+		}
+	}
+	return id
+}
+
+func (t *TileMap) PlaceInteractable(in *Interactable) error {
+	tree, err := loadInteractableDialog(in)
+	if err != nil {
+		return err
+	}
+	t.Interactables = append(t.Interactables, *in)
+	t.interactableDialogs = append(t.interactableDialogs, tree)
+	return nil
+}
+
+func (t *TileMap) RemoveInteractable(index int) {
+	copy(t.Interactables[index:], t.Interactables[index + 1:])
+	copy(t.interactableDialogs[index:], t.interactableDialogs[index + 1:])
+	t.Interactables = t.Interactables[:len(t.Interactables) - 1]
+	t.interactableDialogs = t.interactableDialogs[:len(t.interactableDialogs) - 1]
+}
+
+func (t *TileMap) InteractableAt(x, y, z int) int {
+	for i := range t.Interactables {
+		if t.Interactables[i].X == x && t.Interactables[i].Y == y && t.Interactables[i].Z == z {
+			return i
+		}
+	}
+	return -1
+}
+
 func (t *TileMap) Contains(x, y int) bool {
 	return x < t.Width && x >= 0 && y < t.Height && y >= 0
 }
@@ -558,6 +807,14 @@ func (t *TileMap) FillCollision(x, y, w, h, z int) {
 	}
 }
 
+func loadTilesets(textures []string) []*tileset.Tileset {
+	tilesets := make([]*tileset.Tileset, len(textures))
+	for i := range textures {
+		tilesets[i] = tileset.Get(textures[i])
+	}
+	return tilesets
+}
+
 func CreateTileMap(width int, height int, texture []string) *TileMap {
 	textureMapping := make([]int, len(texture))
 
@@ -576,6 +833,8 @@ func CreateTileMap(width int, height int, texture []string) *TileMap {
 	ind[0] = make([]int, width * height)
 
 	tiles := &TileMap{
+		mapfile.FormatVersion,
+		mapfile.EncodingJSON,
 		tex,
 		col,
 		ind,
@@ -585,8 +844,14 @@ func CreateTileMap(width int, height int, texture []string) *TileMap {
 		width,
 		height,
 		make([]NpcInfo, 0),
+		make([]Trigger, 0),
+		make([]Interactable, 0),
+		MapProperties{},
 		textureMapping,
+		loadTilesets(texture),
 		make([]Npc, 0),
+		make([]*dialog.DialogTree, 0),
+		make([]*dialog.DialogTree, 0),
 	}
 	return tiles
 }
@@ -594,9 +859,48 @@ func CreateTileMap(width int, height int, texture []string) *TileMap {
 func (t *TileMap) createNpcs() error {
 
 	for i := range t.NpcInfo {
-		npc := BuildNpcFromNpcInfo(t, &t.NpcInfo[i])
+		npc, err := BuildNpcFromNpcInfo(t, &t.NpcInfo[i])
+		if err != nil {
+			return fmt.Errorf("Npc at (%d, %d): %s", t.NpcInfo[i].X, t.NpcInfo[i].Y, err.Error())
+		}
 		t.npcs = append(t.npcs, npc)
 	}
 
 	return nil
 }
+
+// loadTriggerDialog validates tr and reads its dialog tree, if it has one
+func loadTriggerDialog(tr *Trigger) (*dialog.DialogTree, error) {
+	if err := tr.Validate(); err != nil {
+		return nil, err
+	}
+	if tr.DialogPath == "" {
+		return nil, nil
+	}
+	return loadDialogTree(tr.DialogPath)
+}
+
+func (t *TileMap) loadTriggers() error {
+	t.triggerDialogs = make([]*dialog.DialogTree, len(t.Triggers))
+	for i := range t.Triggers {
+		tree, err := loadTriggerDialog(&t.Triggers[i])
+		if err != nil {
+			return fmt.Errorf("Trigger %d: %s", t.Triggers[i].Id, err.Error())
+		}
+		t.triggerDialogs[i] = tree
+	}
+	return nil
+}
+
+func (t *TileMap) loadInteractables() error {
+	t.interactableDialogs = make([]*dialog.DialogTree, len(t.Interactables))
+	for i := range t.Interactables {
+		in := &t.Interactables[i]
+		tree, err := loadInteractableDialog(in)
+		if err != nil {
+			return fmt.Errorf("Interactable at (%d, %d, %d): %s", in.X, in.Y, in.Z, err.Error())
+		}
+		t.interactableDialogs[i] = tree
+	}
+	return nil
+}
@@ -3,12 +3,13 @@ package pok
 import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"image/color"
+	"log"
 )
 
 type TransitionState struct {
 	Ticks int
 
-	file string
+	load *MapLoad
 	exitId int
 	magnitude int
 	fadeFrom *ebiten.Image
@@ -17,13 +18,15 @@ type TransitionState struct {
 
 const nTransitionTicks = 10
 
-func NewTransitionState(src *ebiten.Image, file string, exitId int) *TransitionState {
+// NewTransitionState fades from src into the entry with id exitId on the map
+// being opened by load, or back into the current map if it cannot be opened
+func NewTransitionState(src *ebiten.Image, load *MapLoad, exitId int) *TransitionState {
 	img := ebiten.NewImageFromImage(src)
 	fade := ebiten.NewImage(img.Bounds().Max.X, img.Bounds().Max.Y)
 	fade.Fill(color.RGBA{0, 0, 0, 0})
 	return &TransitionState{
 		0,
-		file,
+		load,
 		exitId,
 		1,
 		img,
@@ -38,7 +41,19 @@ func (t *TransitionState) GetInputs(g *Game) error {
 func (t *TransitionState) Update(g *Game) error {
 	t.Ticks += t.magnitude
 	if t.Ticks > nTransitionTicks {
-		g.Load(t.file, t.exitId)
+		// The screen stays dark until the map has been opened
+		if !t.load.Done() {
+			t.Ticks = nTransitionTicks
+			return nil
+		}
+		tm, err := t.load.Wait()
+		if err == nil {
+			g.Enter(tm, t.load.Path, t.exitId)
+		} else {
+			// A map which cannot be opened should not end the game, so the
+			// screen fades back in with the player left on the door
+			log.Println("Could not enter", t.load.Path + ":", err)
+		}
 		g.Ows.Update(g)
 		g.Ows.Draw(g, t.fadeFrom)
 		t.magnitude = -1;
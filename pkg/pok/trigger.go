@@ -0,0 +1,105 @@
+package pok
+
+import(
+	"errors"
+	"fmt"
+	"github.com/atemmel/pok/pkg/dialog"
+	"github.com/atemmel/pok/pkg/mapfile"
+	"github.com/atemmel/pok/pkg/world"
+	"strconv"
+)
+
+// TriggerKind decides how the player sets a trigger off
+type TriggerKind int
+
+const(
+	// Fires every time the player finishes a step inside the area
+	StepTrigger TriggerKind = iota
+	// Fires when the player faces a tile of the area and presses interact
+	InteractTrigger
+	// Fires when the player steps into the area from outside of it
+	AreaTrigger
+	NTriggerKinds
+)
+
+var TriggerKindNames = [NTriggerKinds]string{
+	"Step",
+	"Interact",
+	"Area",
+}
+
+// TriggerCondition compares a game flag against a value using the same
+// operations as dialog branches
+type TriggerCondition = mapfile.Condition
+
+// Trigger is a rectangle of tiles on a layer which either shows a dialog tree
+// or performs an effect when set off. Triggers with conditions only fire
+// while every condition holds, and triggers marked Once only fire the first
+// time, which is remembered through a game flag.
+type Trigger struct {
+	Id int
+	Kind TriggerKind
+	X, Y, Z int
+	W, H int
+	DialogPath string
+	Effect string
+	Conditions []TriggerCondition
+	Once bool
+}
+
+// TriggerFlag is raised once the trigger with id on the map at location has
+// fired, if the trigger only fires once. Maps are named relative to the
+// tilemap directory, so that the flag does not depend on how the path to the
+// map was written.
+func TriggerFlag(location string, id int) string {
+	name, ok := worldName(location)
+	if !ok {
+		name = world.Name(location)
+	}
+	return "trigger:" + name + ":" + strconv.Itoa(id)
+}
+
+func (tr *Trigger) Contains(x, y, z int) bool {
+	return z == tr.Z && x >= tr.X && x < tr.X + tr.W && y >= tr.Y && y < tr.Y + tr.H
+}
+
+// Ready reports whether the trigger may fire given the flags of the game
+func (tr *Trigger) Ready(flags dialog.Flags, location string) bool {
+	if tr.Once && flags.Has(TriggerFlag(location, tr.Id)) {
+		return false
+	}
+	for _, c := range tr.Conditions {
+		if !flags.Compare(c.Flag, c.Operation, c.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the trigger is well formed, without looking at its
+// dialog tree
+func (tr *Trigger) Validate() error {
+	if tr.Kind < 0 || tr.Kind >= NTriggerKinds {
+		return fmt.Errorf("Unknown trigger kind %d", tr.Kind)
+	}
+	if tr.W < 1 || tr.H < 1 {
+		return fmt.Errorf("Trigger is %dx%d tiles", tr.W, tr.H)
+	}
+	if tr.DialogPath == "" && tr.Effect == "" {
+		return errors.New("Trigger has neither a dialog nor an effect")
+	}
+	if tr.DialogPath != "" && tr.Effect != "" {
+		return errors.New("Trigger has both a dialog and an effect")
+	}
+	if tr.Effect != "" {
+		if _, _, err := lookupEffect(tr.Effect); err != nil {
+			return err
+		}
+	}
+	for _, c := range tr.Conditions {
+		if !dialog.IsValidOperation(c.Operation) {
+			return errors.New("Unknown operation \"" + c.Operation + "\" in condition on " + c.Flag)
+		}
+	}
+	return nil
+}
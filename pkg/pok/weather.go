@@ -0,0 +1,126 @@
+package pok
+
+import(
+	"github.com/atemmel/pok/pkg/constants"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"math/rand"
+)
+
+type Weather int
+
+const(
+	Clear Weather = iota
+	Rain
+	Snow
+	Fog
+	NWeathers
+)
+
+var WeatherNames = [NWeathers]string{
+	"Clear",
+	"Rain",
+	"Snow",
+	"Fog",
+}
+
+// How much each weather darkens the colors of the overworld
+var weatherTints = [NWeathers][3]float64{
+	{1, 1, 1},
+	{0.8, 0.8, 0.9},
+	{0.95, 0.95, 1},
+	{0.9, 0.9, 0.9},
+}
+
+const(
+	nRainDrops = 80
+	nSnowFlakes = 60
+	rainDropLength = 6
+)
+
+var rainClr = color.RGBA{180, 190, 255, 160}
+var snowClr = color.RGBA{255, 255, 255, 220}
+var fogClr = color.RGBA{230, 230, 235, 70}
+
+type weatherParticle struct {
+	x, y float64
+	speed float64
+}
+
+// WeatherEffect draws the weather of the current map on top of the screen
+type WeatherEffect struct {
+	Kind Weather
+	particles []weatherParticle
+}
+
+func (w *WeatherEffect) Set(kind Weather) {
+	if kind < 0 || kind >= NWeathers {
+		kind = Clear
+	}
+	if kind == w.Kind && w.particles != nil {
+		return
+	}
+
+	w.Kind = kind
+	n := 0
+	switch kind {
+		case Rain:
+			n = nRainDrops
+		case Snow:
+			n = nSnowFlakes
+	}
+
+	w.particles = make([]weatherParticle, n)
+	for i := range w.particles {
+		w.particles[i] = weatherParticle{
+			rand.Float64() * constants.DisplaySizeX,
+			rand.Float64() * constants.DisplaySizeY,
+			1 + rand.Float64(),
+		}
+	}
+}
+
+// Tint returns how much the weather darkens each color channel
+func (w *WeatherEffect) Tint() (float64, float64, float64) {
+	t := weatherTints[w.Kind]
+	return t[0], t[1], t[2]
+}
+
+func (w *WeatherEffect) Update() {
+	for i := range w.particles {
+		p := &w.particles[i]
+		switch w.Kind {
+			case Rain:
+				p.x -= p.speed
+				p.y += p.speed * 4
+			case Snow:
+				p.x += rand.Float64() - 0.5
+				p.y += p.speed * 0.5
+		}
+
+		if p.y > constants.DisplaySizeY {
+			p.y -= constants.DisplaySizeY
+		}
+		if p.x < 0 {
+			p.x += constants.DisplaySizeX
+		} else if p.x > constants.DisplaySizeX {
+			p.x -= constants.DisplaySizeX
+		}
+	}
+}
+
+func (w *WeatherEffect) Draw(screen *ebiten.Image) {
+	switch w.Kind {
+		case Rain:
+			for _, p := range w.particles {
+				ebitenutil.DrawLine(screen, p.x, p.y, p.x - rainDropLength / 4, p.y + rainDropLength, rainClr)
+			}
+		case Snow:
+			for _, p := range w.particles {
+				ebitenutil.DrawRect(screen, p.x, p.y, 2, 2, snowClr)
+			}
+		case Fog:
+			ebitenutil.DrawRect(screen, 0, 0, constants.DisplaySizeX, constants.DisplaySizeY, fogClr)
+	}
+}
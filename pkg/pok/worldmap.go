@@ -0,0 +1,169 @@
+package pok
+
+import(
+	"github.com/atemmel/pok/pkg/constants"
+	"github.com/atemmel/pok/pkg/world"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// Neighbour is a map bordering the current one, placed X and Y tiles from
+// the top left corner of the current map. It is opened in the background and
+// has no tileMap until it has finished loading, or at all if it could not be
+// opened, in which case err tells why
+type Neighbour struct {
+	tileMap *TileMap
+	load *MapLoad
+	err error
+	path string
+	X, Y int
+}
+
+// worldName returns the name of the map at path within the world, reporting
+// false for maps outside of the tilemap directory
+func worldName(path string) (string, bool) {
+	dir, err := filepath.Abs(constants.TileMapDir)
+	if err != nil {
+		return "", false
+	}
+	file, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return world.Name(rel), true
+}
+
+// LoadWorld reads the world file at path, which places maps next to each other
+func (g *Game) LoadWorld(path string) error {
+	w, err := world.Read(path)
+	if err != nil {
+		return err
+	}
+	g.World = w
+	return nil
+}
+
+// loadNeighbours starts opening the maps bordering the map at path, reusing
+// those of keep which are already loaded or loading
+func (g *Game) loadNeighbours(path string, keep []Neighbour) {
+	g.Ows.neighbours = make([]Neighbour, 0)
+	name, ok := worldName(path)
+	if !ok || g.World == nil {
+		return
+	}
+	i := g.World.Find(name)
+	if i == -1 {
+		return
+	}
+	self := g.World.Maps[i]
+
+	for _, p := range g.World.Neighbours(name) {
+		n := Neighbour{nil, nil, nil, constants.TileMapDir + p.Map, p.X - self.X, p.Y - self.Y}
+		for _, k := range keep {
+			if k.path == n.path {
+				n.tileMap, n.load, n.err = k.tileMap, k.load, k.err
+				break
+			}
+		}
+		if n.tileMap == nil && n.load == nil && n.err == nil {
+			n.load = LoadMapAsync(n.path)
+		}
+		g.Ows.neighbours = append(g.Ows.neighbours, n)
+	}
+}
+
+// updateNeighbours takes in the neighbouring maps which have finished loading.
+// A neighbour which could not be opened is left without a map, so that its
+// tiles block the player like a wall.
+func (o *OverworldState) updateNeighbours() {
+	for i := range o.neighbours {
+		n := &o.neighbours[i]
+		if n.load == nil || !n.load.Done() {
+			continue
+		}
+		n.tileMap, n.err = n.load.Wait()
+		n.load = nil
+		if n.err != nil {
+			log.Println("Could not open neighbouring map", n.path + ":", n.err)
+		}
+	}
+}
+
+// mapAt returns the loaded map holding the tile at x, y of the current map,
+// along with the position of the tile within that map. Tiles of neighbours
+// which are loading or failed to load are in no map.
+func (o *OverworldState) mapAt(x, y int) (*TileMap, int, int) {
+	if inside(&o.tileMap, x, y) {
+		return &o.tileMap, x, y
+	}
+	for _, n := range o.neighbours {
+		if n.tileMap != nil && inside(n.tileMap, x - n.X, y - n.Y) {
+			return n.tileMap, x - n.X, y - n.Y
+		}
+	}
+	return nil, 0, 0
+}
+
+func inside(t *TileMap, x, y int) bool {
+	return x >= 0 && x < t.Width && y >= 0 && y < t.Height
+}
+
+// hasTag reports whether the tile at x, y of the current map is tagged with
+// tag, looking into the neighbouring maps past its edges
+func (o *OverworldState) hasTag(x, y, z int, tag string) bool {
+	tm, x, y := o.mapAt(x, y)
+	if tm == nil {
+		return false
+	}
+	return tm.HasTag(tm.Index(x, y), z, tag)
+}
+
+// crossBorder makes the neighbouring map the player has walked onto the
+// current one, without any transition
+func (g *Game) crossBorder() {
+	if inside(&g.Ows.tileMap, g.Player.Char.X, g.Player.Char.Y) {
+		return
+	}
+
+	found := -1
+	for i, n := range g.Ows.neighbours {
+		if n.tileMap != nil && inside(n.tileMap, g.Player.Char.X - n.X, g.Player.Char.Y - n.Y) {
+			found = i
+			break
+		}
+	}
+	if found == -1 {
+		return
+	}
+	n := g.Ows.neighbours[found]
+
+	prev := g.Ows.tileMap
+	keep := append(g.Ows.neighbours, Neighbour{&prev, nil, nil, g.Player.Location, -n.X, -n.Y})
+	g.Ows.tileMap = *n.tileMap
+
+	c := &g.Player.Char
+	c.X -= n.X
+	c.Y -= n.Y
+	c.Gx -= float64(n.X * constants.TileSize)
+	c.Gy -= float64(n.Y * constants.TileSize)
+	g.Player.prevX -= n.X
+	g.Player.prevY -= n.Y
+	g.Player.Location = n.path
+
+	g.loadNeighbours(n.path, keep)
+	g.ApplyMapProperties()
+}
+
+func (o *OverworldState) drawNeighbours(rend *Renderer) {
+	for _, n := range o.neighbours {
+		if n.tileMap == nil {
+			continue
+		}
+		n.tileMap.DrawWithOffset(rend, float64(n.X * constants.TileSize), float64(n.Y * constants.TileSize))
+	}
+}
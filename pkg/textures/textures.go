@@ -4,7 +4,7 @@ import(
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/atemmel/pok/pkg/debug"
-	"github.com/atemmel/pok/pkg/constants"
+	"sync"
 
 	_ "image/png"
 )
@@ -12,19 +12,14 @@ import(
 var(
 	aliases map[string]int
 	textures []*ebiten.Image
-
-	baseTextureIndex = InvalidIndex
-	waterTextureIndex = InvalidIndex
-	stairTextureIndex = InvalidIndex
+	// Maps may be opened in the background while others are drawn
+	mutex sync.RWMutex
 )
 
 const(
 	InvalidIndex = -1
 
 	preAlloc = 8
-	baseTextureStr = constants.TileMapImagesDir + "base.png"
-	waterTextureStr = constants.TileMapImagesDir + "water.png"
-	stairTextureStr = constants.TileMapImagesDir + "stairs.png"
 )
 
 func Init() {
@@ -33,11 +28,21 @@ func Init() {
 }
 
 func Load(path string) (*ebiten.Image, int) {
+	img, index, err := TryLoad(path)
+	debug.Assert(err)
+	return img, index
+}
+
+// TryLoad is Load, returning an error instead of asserting when the image
+// could not be read
+func TryLoad(path string) (*ebiten.Image, int, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
 	index, ok := aliases[path]
 	if !ok {
 		return insertNewTexture(path);
 	}
-	return Access(index), index
+	return textures[index], index, nil
 }
 
 func LoadWithError(path string) (*ebiten.Image, error) {
@@ -46,47 +51,27 @@ func LoadWithError(path string) (*ebiten.Image, error) {
 }
 
 func Access(index int) *ebiten.Image {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return textures[index];
 }
 
-func IsWater(index int) bool {
-	return index == waterTextureIndex
-}
-
-func IsBase(index int) bool {
-	return index == baseTextureIndex
-}
-
-func IsStair(index int) bool {
-	return index == stairTextureIndex
-}
-
-func insertNewTexture(path string) (*ebiten.Image, int) {
+func insertNewTexture(path string) (*ebiten.Image, int, error) {
 	img, _, err := ebitenutil.NewImageFromFile(path)
-	debug.Assert(err)
+	if err != nil {
+		return nil, InvalidIndex, err
+	}
 
 	for i, ptr := range textures {
 		if ptr == nil {
 			aliases[path] = i
 			textures[i] = img
-			checkForSpecialTextures(path, i)
-			return img, i
+			return img, i, nil
 		}
 	}
 
 	i := len(textures)
 	aliases[path] = i
 	textures = append(textures, img)
-	checkForSpecialTextures(path, i)
-	return img, i
-}
-
-func checkForSpecialTextures(path string, index int) {
-	if baseTextureIndex == InvalidIndex && baseTextureStr == path {
-		baseTextureIndex = index
-	} else if waterTextureIndex == InvalidIndex && waterTextureStr == path {
-		waterTextureIndex = index
-	} else if stairTextureIndex == InvalidIndex && stairTextureStr == path {
-		stairTextureIndex = index
-	}
+	return img, i, nil
 }
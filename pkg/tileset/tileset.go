@@ -0,0 +1,184 @@
+package tileset
+
+import(
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Every texture used for tiles may have a file of the same name in the
+// tileset directory, listing which of its tiles carry which tags:
+//
+//	{
+//		"Texture": "water.png",
+//		"Tags": {
+//			"deep-water": [67]
+//		},
+//		"Animations": [
+//			{"Tiles": [67], "Frames": 11, "Stride": 6, "Duration": 11}
+//		]
+//	}
+
+const Extension = ".json"
+
+const(
+	// The tile may be jumped down from, and blocks any other direction
+	LedgeDown = "ledge-down"
+	LedgeLeft = "ledge-left"
+	LedgeRight = "ledge-right"
+	// The tile is part of a staircase, which is climbed or descended when
+	// walked onto or off of
+	Stair = "stair"
+	// Stepping right from the tile moves up a staircase and stepping left
+	// moves down, or the other way around for StairUpLeft
+	StairUpRight = "stair-up-right"
+	StairUpLeft = "stair-up-left"
+	// The tile splashes when walked on
+	Water = "water"
+	// The tile can only be crossed while surfing
+	DeepWater = "deep-water"
+	// Wild encounters may happen on the tile
+	TallGrass = "tall-grass"
+)
+
+var Tags = []string{
+	LedgeDown,
+	LedgeLeft,
+	LedgeRight,
+	Stair,
+	StairUpRight,
+	StairUpLeft,
+	Water,
+	DeepWater,
+	TallGrass,
+}
+
+// Animation cycles tiles through Frames frames shown for Duration ticks
+// each, where every frame lies Stride tiles after the one before it
+type Animation struct {
+	Tiles []int
+	Frames int
+	Stride int
+	Duration int
+}
+
+type Tileset struct {
+	Texture string
+	Tags map[string][]int
+	Animations []Animation
+
+	tagged map[string]map[int]bool
+	animated map[int]*Animation
+}
+
+var tilesets = make(map[string]*Tileset)
+
+// empty stands in for textures without a tileset file
+var empty = &Tileset{}
+
+func IsValidTag(tag string) bool {
+	for _, t := range Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func Read(path string) (*Tileset, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &Tileset{}
+	if err = json.Unmarshal(bytes, ts); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	if ts.Texture == "" {
+		return nil, fmt.Errorf("%s: Tileset has no texture", path)
+	}
+
+	ts.tagged = make(map[string]map[int]bool, len(ts.Tags))
+	for tag, tiles := range ts.Tags {
+		if !IsValidTag(tag) {
+			return nil, fmt.Errorf("%s: Unknown tag %q, expected one of %s", path, tag, strings.Join(Tags, ", "))
+		}
+		ts.tagged[tag] = make(map[int]bool, len(tiles))
+		for _, tile := range tiles {
+			if tile < 0 {
+				return nil, fmt.Errorf("%s: Tag %q lists negative tile %d", path, tag, tile)
+			}
+			ts.tagged[tag][tile] = true
+		}
+	}
+
+	ts.animated = make(map[int]*Animation)
+	for i := range ts.Animations {
+		a := &ts.Animations[i]
+		if a.Frames < 1 || a.Stride < 1 || a.Duration < 1 {
+			return nil, fmt.Errorf("%s: Animation %d needs at least one frame, a stride and a duration", path, i)
+		}
+		for _, tile := range a.Tiles {
+			if tile < 0 {
+				return nil, fmt.Errorf("%s: Animation %d lists negative tile %d", path, i, tile)
+			}
+			if _, ok := ts.animated[tile]; ok {
+				return nil, fmt.Errorf("%s: Tile %d is animated more than once", path, tile)
+			}
+			ts.animated[tile] = a
+		}
+	}
+
+	return ts, nil
+}
+
+// LoadAll reads every tileset in dir, replacing those read before
+func LoadAll(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	loaded := make(map[string]*Tileset, len(infos))
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != Extension {
+			continue
+		}
+		ts, err := Read(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return err
+		}
+		if _, ok := loaded[ts.Texture]; ok {
+			return fmt.Errorf("%s: Texture %s already has a tileset", info.Name(), ts.Texture)
+		}
+		loaded[ts.Texture] = ts
+	}
+
+	tilesets = loaded
+	return nil
+}
+
+// Get returns the tileset of texture, which has no tags if none was loaded
+func Get(texture string) *Tileset {
+	if ts, ok := tilesets[texture]; ok {
+		return ts
+	}
+	return empty
+}
+
+func (ts *Tileset) Has(tile int, tag string) bool {
+	return ts.tagged[tag][tile]
+}
+
+// Animate returns the tile to draw in place of tile after ticks ticks
+func (ts *Tileset) Animate(tile, ticks int) int {
+	a, ok := ts.animated[tile]
+	if !ok {
+		return tile
+	}
+	return tile + (ticks / a.Duration) % a.Frames * a.Stride
+}
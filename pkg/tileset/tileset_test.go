@@ -0,0 +1,170 @@
+package tileset
+
+import(
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTileset(t *testing.T, dir, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tileset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTileset(t, dir, "a.json", `{"Texture":"a.png","Tags":{"ledge-down":[1,2],"water":[2]}}`)
+	writeTileset(t, dir, "notes.txt", `not a tileset`)
+	if err = LoadAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	type hasTest struct {
+		Texture string
+		Tile int
+		Tag string
+		Expected bool
+	}
+
+	tests := []hasTest{
+		{"a.png", 1, LedgeDown, true},
+		{"a.png", 2, LedgeDown, true},
+		{"a.png", 2, Water, true},
+		{"a.png", 1, Water, false},
+		{"a.png", 3, LedgeDown, false},
+		{"b.png", 1, LedgeDown, false},
+	}
+
+	for _, test := range tests {
+		if got := Get(test.Texture).Has(test.Tile, test.Tag); got != test.Expected {
+			t.Errorf("Tile %d of %s tagged %s: expected %v, got %v", test.Tile, test.Texture, test.Tag, test.Expected, got)
+		}
+	}
+}
+
+func TestLoadAllErrors(t *testing.T) {
+	inputs := [][]string{
+		{`{"Texture":"a.png","Tags":{"ledge-up":[1]}}`},
+		{`{"Tags":{"water":[1]}}`},
+		{`{"Texture":"a.png","Tags":{"water":[-1]}}`},
+		{`{"Texture":"a.png"}`, `{"Texture":"a.png"}`},
+		{`{"Texture":"a.png","Animations":[{"Tiles":[1],"Frames":0,"Stride":1,"Duration":1}]}`},
+		{`{"Texture":"a.png","Animations":[{"Tiles":[1],"Frames":2,"Stride":1,"Duration":1},{"Tiles":[1],"Frames":2,"Stride":1,"Duration":1}]}`},
+	}
+
+	for _, files := range inputs {
+		dir, err := ioutil.TempDir("", "tileset")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, content := range files {
+			writeTileset(t, dir, string(rune('a' + i)) + Extension, content)
+		}
+		if err = LoadAll(dir); err == nil {
+			t.Errorf("%v: expected an error", files)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// nTiles returns how many tiles the overworld image texture holds
+func nTiles(t *testing.T, texture string) int {
+	file, err := os.Open(filepath.Join("../../resources/images/overworld", texture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	config, err := png.DecodeConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return (config.Width / 16) * (config.Height / 16)
+}
+
+func TestResources(t *testing.T) {
+	if err := LoadAll("../../resources/tilesets"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tiles each tag was given by hand before tilesets existed
+	type resourceTest struct {
+		Texture string
+		Tag string
+		Has func(tile int) bool
+	}
+
+	among := func(tiles ...int) func(int) bool {
+		return func(tile int) bool {
+			for _, t := range tiles {
+				if t == tile {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []resourceTest{
+		{"base.png", LedgeDown, among(213, 214, 215)},
+		{"base.png", LedgeLeft, among(232, 240, 248)},
+		{"base.png", LedgeRight, among(233, 241, 249)},
+		{"water.png", DeepWater, among(67)},
+		{"stairs.png", Stair, func(tile int) bool {
+			return !among(170, 192, 214)(tile)
+		}},
+		{"stairs.png", StairUpRight, among(170, 171, 192, 193, 214, 215)},
+		{"stairs.png", StairUpLeft, among()},
+	}
+
+	for _, test := range tests {
+		ts, n := Get(test.Texture), nTiles(t, test.Texture)
+		for tile := 0; tile < n; tile++ {
+			if got := ts.Has(tile, test.Tag); got != test.Has(tile) {
+				t.Errorf("Tile %d of %s tagged %s: expected %v, got %v", tile, test.Texture, test.Tag, test.Has(tile), got)
+			}
+		}
+	}
+}
+
+func TestAnimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tileset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTileset(t, dir, "a.json", `{"Texture":"a.png","Animations":[{"Tiles":[1,2],"Frames":3,"Stride":10,"Duration":4}]}`)
+	if err = LoadAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	type animateTest struct {
+		Tile int
+		Ticks int
+		Expected int
+	}
+
+	tests := []animateTest{
+		{1, 0, 1},
+		{1, 3, 1},
+		{1, 4, 11},
+		{2, 8, 22},
+		{2, 12, 2},
+		{3, 4, 3},
+	}
+
+	ts := Get("a.png")
+	for _, test := range tests {
+		if got := ts.Animate(test.Tile, test.Ticks); got != test.Expected {
+			t.Errorf("Tile %d after %d ticks: expected %d, got %d", test.Tile, test.Ticks, test.Expected, got)
+		}
+	}
+}
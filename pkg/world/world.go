@@ -0,0 +1,129 @@
+package world
+
+import(
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// The world file places maps in one shared space, measured in tiles, so that
+// maps lying next to each other can be shown and walked between:
+//
+//	{
+//		"Maps": [
+//			{"Map": "town.json", "X": 0, "Y": 0, "Width": 20, "Height": 16},
+//			{"Map": "route_1.json", "X": 4, "Y": -30, "Width": 12, "Height": 30}
+//		]
+//	}
+//
+// Maps are named by their path relative to the tilemap directory.
+
+// Placement puts a map at a position of the world
+type Placement struct {
+	Map string
+	X, Y int
+	Width, Height int
+}
+
+type World struct {
+	Maps []Placement
+}
+
+func New() *World {
+	return &World{make([]Placement, 0)}
+}
+
+// Read reads the world file at path, where a missing file is an empty world
+func Read(path string) (*World, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return New(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	w := New()
+	if err = json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	seen := make(map[string]bool)
+	for i := range w.Maps {
+		p := &w.Maps[i]
+		p.Map = Name(p.Map)
+		if seen[p.Map] {
+			return nil, fmt.Errorf("%s: %s is placed more than once", path, p.Map)
+		}
+		if p.Width <= 0 || p.Height <= 0 {
+			return nil, fmt.Errorf("%s: %s has no size", path, p.Map)
+		}
+		seen[p.Map] = true
+	}
+	return w, nil
+}
+
+func (w *World) Write(path string) error {
+	data, err := json.MarshalIndent(w, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Name turns a path to a map relative to the tilemap directory into the name
+// the world knows it by
+func Name(m string) string {
+	return path.Clean(filepath.ToSlash(m))
+}
+
+// Find returns the index of the placement of m, or -1
+func (w *World) Find(m string) int {
+	m = Name(m)
+	for i := range w.Maps {
+		if w.Maps[i].Map == m {
+			return i
+		}
+	}
+	return -1
+}
+
+// Place puts a map in the world, moving it if it already was
+func (w *World) Place(p Placement) {
+	p.Map = Name(p.Map)
+	if i := w.Find(p.Map); i != -1 {
+		w.Maps[i] = p
+		return
+	}
+	w.Maps = append(w.Maps, p)
+}
+
+// Rect returns the tiles the placement covers
+func (p *Placement) Rect() image.Rectangle {
+	return image.Rect(p.X, p.Y, p.X + p.Width, p.Y + p.Height)
+}
+
+// Neighbours returns the maps which border or overlap m. Maps which only
+// touch m at a corner cannot be walked into from it and are left out.
+func (w *World) Neighbours(m string) []Placement {
+	neighbours := make([]Placement, 0)
+	i := w.Find(m)
+	if i == -1 {
+		return neighbours
+	}
+
+	// Growing the map by a tile along one axis at a time makes maps along its
+	// edges overlap it, but not those diagonal to it
+	r := w.Maps[i].Rect()
+	wide := image.Rect(r.Min.X - 1, r.Min.Y, r.Max.X + 1, r.Max.Y)
+	tall := image.Rect(r.Min.X, r.Min.Y - 1, r.Max.X, r.Max.Y + 1)
+	for j := range w.Maps {
+		other := w.Maps[j].Rect()
+		if j != i && (wide.Overlaps(other) || tall.Overlaps(other)) {
+			neighbours = append(neighbours, w.Maps[j])
+		}
+	}
+	return neighbours
+}
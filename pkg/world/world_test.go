@@ -0,0 +1,99 @@
+package world
+
+import(
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "world.json")
+
+	w, err := Read(path)
+	if err != nil || len(w.Maps) != 0 {
+		t.Fatalf("Expected a missing world to be empty, got %v %v", w, err)
+	}
+
+	w.Place(Placement{"town.json", 0, 0, 20, 16})
+	w.Place(Placement{"./routes/../route_1.json", 4, -30, 12, 30})
+	w.Place(Placement{"town.json", 1, 2, 20, 16})
+	if err = w.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Placement{
+		{"town.json", 1, 2, 20, 16},
+		{"route_1.json", 4, -30, 12, 30},
+	}
+	if !reflect.DeepEqual(got.Maps, want) {
+		t.Errorf("Expected %v, got %v", want, got.Maps)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "world.json")
+
+	inputs := []string{
+		`{"Maps": [`,
+		`{"Maps": [{"Map": "a.json", "Width": 1, "Height": 1}, {"Map": "./a.json", "Width": 1, "Height": 1}]}`,
+		`{"Maps": [{"Map": "a.json", "Width": 0, "Height": 1}]}`,
+	}
+
+	for _, input := range inputs {
+		if err = ioutil.WriteFile(path, []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = Read(path); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	w := New()
+	w.Place(Placement{"center.json", 0, 0, 10, 10})
+	w.Place(Placement{"north.json", 2, -5, 4, 5})
+	w.Place(Placement{"east.json", 10, 3, 6, 6})
+	w.Place(Placement{"far.json", 12, -8, 2, 2})
+	w.Place(Placement{"corner.json", -3, 10, 3, 3})
+	w.Place(Placement{"south.json", 8, 10, 5, 3})
+
+	type neighbourTest struct {
+		Map string
+		Expected []string
+	}
+
+	tests := []neighbourTest{
+		{"center.json", []string{"north.json", "east.json", "south.json"}},
+		{"north.json", []string{"center.json"}},
+		{"corner.json", []string{}},
+		{"far.json", []string{}},
+		{"missing.json", []string{}},
+	}
+
+	for _, test := range tests {
+		got := make([]string, 0)
+		for _, p := range w.Neighbours(test.Map) {
+			got = append(got, p.Map)
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("%s: expected %v, got %v", test.Map, test.Expected, got)
+		}
+	}
+}